@@ -98,18 +98,26 @@ type App struct {
 	mutex sync.Mutex
 	// Route stack divided by HTTP methods
 	stack [][]*Route
-	// Route stack divided by HTTP methods and route prefixes
-	treeStack []map[string][]*Route
+	// Radix trees of the route stack divided by HTTP methods, the []*routeTree is replaced atomically after route changes
+	treeStack atomic.Value
 	// contains the information if the route stack has been changed to build the optimized tree
 	routesRefreshed bool
+	// contains the information if the tree has been built, later route changes are published immediately
+	treeBuilt bool
+	// conflicts of the route stack which are found when the tree is built
+	routeConflicts []string
+	// contains the information if the request methods start with the default methods
+	defaultMethods bool
+	// index of the first custom request method
+	customMethods int
 	// Amount of registered routes
 	routesCount uint32
+	// Position of the latest route, it only increases so that the positions stay unique after a removal
+	routesPos uint32
 	// Amount of registered handlers
 	handlersCount uint32
 	// Ctx pool
 	pool sync.Pool
-	// Req/Res pool
-	rrPool sync.Pool
 	// Fasthttp server
 	server *fasthttp.Server
 	// App config
@@ -120,6 +128,10 @@ type App struct {
 	getString func(b []byte) string
 	// mount prefix -> error handler
 	errorHandlers map[string]ErrorHandler
+	// Renderers of res.Negotiate in the order of preference, a []mediaRenderer which is replaced on every change
+	renderers atomic.Value
+	// media type -> decoder of BodyParser, a map[string]Decoder which is replaced on every change
+	decoders atomic.Value
 }
 
 // Config is a struct holding the server settings.
@@ -249,6 +261,44 @@ type Config struct {
 	// Default: false
 	GETOnly bool `json:"get_only"`
 
+	// When set to true, OPTIONS requests without a matching OPTIONS route are answered
+	// with 204 No Content and an Allow header listing the methods registered for the path.
+	//
+	// Default: false
+	AutoOptions bool `json:"auto_options"`
+
+	// When set to true, HEAD requests without a matching HEAD route are served
+	// by the GET routes of the path, the response body is stripped.
+	//
+	// Default: false
+	AutoHead bool `json:"auto_head"`
+
+	// RouteConflictPolicy defines how exact duplicates, routes which are shadowed by earlier
+	// routes and parameters with different names at the same position are reported.
+	// RouteConflictWarn prints them with the startup message, RouteConflictPanic panics at the startup.
+	//
+	// Default: RouteConflictIgnore
+	RouteConflictPolicy RouteConflictPolicy `json:"route_conflict_policy"`
+
+	// VersionExtractor returns the requested API version for the routes of app.Version,
+	// an empty string means that the request doesn't ask for a version.
+	//
+	// Default: DefaultVersionExtractor
+	VersionExtractor func(req *Request) string `json:"-"`
+
+	// DefaultVersion is used for the requests without a version. If it's empty,
+	// these requests only match the routes without a version.
+	//
+	// Default: ""
+	DefaultVersion string `json:"default_version"`
+
+	// RequestMethods are the HTTP methods which are accepted by the router, every method gets its own
+	// route stack. Custom methods like PURGE or the WebDAV methods can be added to the default methods.
+	//  RequestMethods: append(lightning.DefaultMethods(), "PURGE", "PROPFIND", "MKCOL", "LOCK")
+	//
+	// Default: DefaultMethods()
+	RequestMethods []string `json:"request_methods"`
+
 	// ErrorHandler is executed when an error is returned from fiber.Handler.
 	//
 	// Default: DefaultErrorHandler
@@ -299,6 +349,24 @@ type Config struct {
 	// Server pre parses multipart form data by default.
 	DisablePreParseMultipartForm bool
 
+	// MultipartPartLimit is the maximum size of a single part which is read by req.StreamFiles,
+	// a bigger part fails with 413 Request Entity Too Large. Form fields are also limited by BodyLimit.
+	//
+	// Default: 0 (unlimited)
+	MultipartPartLimit int64 `json:"multipart_part_limit"`
+
+	// MultipartTempDir is the directory in which FilePart.Spill creates its temporary files.
+	//
+	// Default: os.TempDir()
+	MultipartTempDir string `json:"multipart_temp_dir"`
+
+	// CookieSigningKeys is the keyring of res.SetSignedCookie and req.SignedCookie, the cookies are
+	// signed with HMAC-SHA256. The last key signs and all keys verify, to rotate append a new key
+	// and remove the old one once its cookies expired. Use at least 32 random bytes per key.
+	//
+	// Default: nil
+	CookieSigningKeys []string `json:"cookie_signing_keys"`
+
 	// Aggressively reduces memory usage at the cost of higher CPU usage
 	// if set to true.
 	//
@@ -309,13 +377,12 @@ type Config struct {
 	// Default: false
 	ReduceMemoryUsage bool `json:"reduce_memory_usage"`
 
-	// FEATURE: v2.3.x
 	// The router executes the same handler by default if StrictRouting or CaseSensitive is disabled.
 	// Enabling RedirectFixedPath will change this behaviour into a client redirect to the original route path.
-	// Using the StatusCode code 301 for GET requests and 308 for all other request methods.
+	// Using the StatusCode code 301 for GET and HEAD requests and 308 for all other request methods.
 	//
 	// Default: false
-	// RedirectFixedPath bool
+	RedirectFixedPath bool `json:"redirect_fixed_path"`
 
 	// When set by an external client of Fiber it will use the provided implementation of a
 	// JSONMarshal
@@ -435,10 +502,7 @@ var latestGroup Group
 
 // DefaultErrorHandler that process return errors from handlers
 var DefaultErrorHandler = func(req *Request, res *Response, err error) error {
-	code := StatusInternalServerError
-	if e, ok := err.(*Error); ok {
-		code = e.Code
-	}
+	code := errorStatus(err)
 	res.Header.Set(HeaderContentType, MIMETextPlainCharsetUTF8)
 	return res.Status(code).String(err.Error())
 }
@@ -453,9 +517,6 @@ var DefaultErrorHandler = func(req *Request, res *Response, err error) error {
 func New(config ...Config) *App {
 	// Create a new app
 	app := &App{
-		// Create router stack
-		stack:     make([][]*Route, len(intMethod)),
-		treeStack: make([]map[string][]*Route, len(intMethod)),
 		// Create Ctx pool
 		pool: sync.Pool{
 			New: func() interface{} {
@@ -473,6 +534,22 @@ func New(config ...Config) *App {
 		app.config = config[0]
 	}
 
+	// Create router stack for the request methods
+	if len(app.config.RequestMethods) == 0 {
+		app.config.RequestMethods = defaultRequestMethods
+	}
+	app.config.RequestMethods = normalizeMethods(app.config.RequestMethods)
+	app.defaultMethods = len(app.config.RequestMethods) >= len(defaultRequestMethods)
+	for i := 0; app.defaultMethods && i < len(defaultRequestMethods); i++ {
+		app.defaultMethods = app.config.RequestMethods[i] == defaultRequestMethods[i]
+	}
+	if app.defaultMethods {
+		app.customMethods = len(defaultRequestMethods)
+	}
+	app.stack = make([][]*Route, len(app.config.RequestMethods))
+	// Empty trees until the routes are registered
+	app.treeStack.Store(make([]*routeTree, len(app.config.RequestMethods)))
+
 	if app.config.ETag {
 		if !IsChild() {
 			fmt.Println("[Warning] Config.ETag is deprecated since v2.0.6, please use 'middleware/etag'.")
@@ -503,6 +580,10 @@ func New(config ...Config) *App {
 		app.config.ErrorHandler = DefaultErrorHandler
 	}
 
+	if app.config.VersionExtractor == nil {
+		app.config.VersionExtractor = DefaultVersionExtractor
+	}
+
 	if app.config.JSONEncoder == nil {
 		app.config.JSONEncoder = json.Marshal
 	}
@@ -518,6 +599,9 @@ func New(config ...Config) *App {
 		app.handleTrustedProxy(ipAddress)
 	}
 
+	// Register the renderers and decoders of the built-in media types
+	app.registerDefaultCodecs()
+
 	// Init app
 	app.init()
 
@@ -549,8 +633,11 @@ func (app *App) Mount(prefix string, lighting *App) Router {
 	stack := lighting.Stack()
 	for m := range stack {
 		for r := range stack[m] {
+			if !app.mountable(stack[m][r]) {
+				continue
+			}
 			route := app.copyRoute(stack[m][r])
-			app.addRoute(route.Method, app.addPrefixToRoute(prefix, route))
+			app.mustAddRoute(app.addPrefixToRoute(prefix, route), route.Method)
 		}
 	}
 
@@ -568,19 +655,34 @@ func (app *App) Mount(prefix string, lighting *App) Router {
 	return app
 }
 
-// Assign name to specific route.
-func (app *App) Name(name string) Router {
-	if strings.HasPrefix(latestRoute.route.path, latestGroup.prefix) {
-		latestRoute.route.Name = latestGroup.name + name
-	} else {
-		latestRoute.route.Name = name
+// mountable checks if the method of the mounted route is accepted by the app. The middlewares of
+// the methods which the app doesn't accept are skipped, all other routes panic.
+func (app *App) mountable(route *Route) bool {
+	if app.methodInt(route.Method) != -1 {
+		return true
+	}
+	if route.use {
+		return false
 	}
+	panic(fmt.Sprintf("mount: method %s of the route %s is not in the RequestMethods of the app\n", route.Method, route.Path))
+}
 
+// Assign name to specific route.
+func (app *App) Name(name string) Router {
+	app.updateLatestRoute(func(route *Route) {
+		if strings.HasPrefix(route.path, latestGroup.prefix) {
+			name = latestGroup.name + name
+		}
+		route.Name = name
+	})
 	return app
 }
 
 // Get route by name
 func (app *App) GetRoute(name string) Route {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
 	for _, routes := range app.stack {
 		for _, route := range routes {
 			if route.Name == name {
@@ -592,6 +694,92 @@ func (app *App) GetRoute(name string) Route {
 	return Route{}
 }
 
+// AddRoute registers a route like Add, but returns the conflicts of the route stack instead of panicking
+// if Config.RouteConflictPolicy is RouteConflictPanic. The route stack is unchanged if an error is returned,
+// so it's the way to add routes while the server is running.
+func (app *App) AddRoute(method, path string, handlers ...Handler) error {
+	return app.registerRoute(method, path, nil, handlers...)
+}
+
+// RemoveRoute removes the routes with the given method and the registered path, the method "USE" removes
+// the middlewares of the path. Running requests finish with the previous routes.
+// Returns false if no route has been removed.
+//  app.RemoveRoute(fiber.MethodGet, "/api/users/:id")
+func (app *App) RemoveRoute(method, path string) bool {
+	method = utils.ToUpper(method)
+	return app.removeRoutes(func(route *Route) bool {
+		if method == methodUse {
+			return route.use && route.Path == path
+		}
+		return !route.use && route.Method == method && route.Path == path
+	})
+}
+
+// RemoveRouteByName removes all routes with the given name.
+// Returns false if no route has been removed.
+func (app *App) RemoveRouteByName(name string) bool {
+	if name == "" {
+		return false
+	}
+	return app.removeRoutes(func(route *Route) bool {
+		return route.Name == name
+	})
+}
+
+// removeRoutes removes the routes which fulfill the condition and publishes the new tree
+func (app *App) removeRoutes(remove func(route *Route) bool) (removed bool) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	var removedRoutes []*Route
+	for m := range app.stack {
+		stack := make([]*Route, 0, len(app.stack[m]))
+		for _, route := range app.stack[m] {
+			if remove(route) {
+				removedRoutes = append(removedRoutes, route)
+				continue
+			}
+			stack = append(stack, route)
+		}
+		app.stack[m] = stack
+	}
+	if len(removedRoutes) == 0 {
+		return false
+	}
+	atomic.AddUint32(&app.routesCount, ^uint32(len(removedRoutes)-1))
+	if handlers := removedHandlers(removedRoutes); handlers > 0 {
+		atomic.AddUint32(&app.handlersCount, ^uint32(handlers-1))
+	}
+	app.routesRefreshed = true
+	// Publish the change at once if the server is already running
+	if app.treeBuilt {
+		app.buildTree()
+	}
+	return true
+}
+
+// removedHandlers counts the handlers of the removed routes like they were counted at the registration,
+// the handlers of a middleware are counted once for all methods and a static route once for GET and HEAD
+func removedHandlers(routes []*Route) (count int) {
+	seen := make(map[*Route]bool, len(routes))
+	middlewares := make(map[string]int)
+	for _, route := range routes {
+		if route.use {
+			key := route.Host + "\x00" + route.Version + "\x00" + route.Path
+			if len(route.Handlers) > middlewares[key] {
+				middlewares[key] = len(route.Handlers)
+			}
+		} else if !seen[route] {
+			seen[route] = true
+			count += len(route.Handlers)
+		}
+	}
+	for _, handlers := range middlewares {
+		count += handlers
+	}
+	return count
+}
+
 // Use registers a middleware route that will match requests
 // with the provided prefix (which is optional and defaults to "/").
 //
@@ -620,7 +808,7 @@ func (app *App) Use(args ...interface{}) Router {
 			panic(fmt.Sprintf("use: invalid handler %v\n", reflect.TypeOf(arg)))
 		}
 	}
-	app.register(methodUse, prefix, handlers...)
+	app.register(methodUse, prefix, nil, handlers...)
 	return app
 }
 
@@ -679,17 +867,17 @@ func (app *App) Patch(path string, handlers ...Handler) Router {
 
 // Add allows you to specify a HTTP method to register a route
 func (app *App) Add(method, path string, handlers ...Handler) Router {
-	return app.register(method, path, handlers...)
+	return app.register(method, path, nil, handlers...)
 }
 
 // Static will create a file server serving static files
 func (app *App) Static(prefix, root string, config ...Static) Router {
-	return app.registerStatic(prefix, root, config...)
+	return app.registerStatic(prefix, root, nil, config...)
 }
 
 // All will register the handler on all HTTP methods
 func (app *App) All(path string, handlers ...Handler) Router {
-	for _, method := range intMethod {
+	for _, method := range app.config.RequestMethods {
 		_ = app.Add(method, path, handlers...)
 	}
 	return app
@@ -700,7 +888,7 @@ func (app *App) All(path string, handlers ...Handler) Router {
 //  api.Get("/users", handler)
 func (app *App) Group(prefix string, handlers ...Handler) Router {
 	if len(handlers) > 0 {
-		app.register(methodUse, prefix, handlers...)
+		app.register(methodUse, prefix, nil, handlers...)
 	}
 	return &Group{prefix: prefix, app: app}
 }
@@ -866,9 +1054,16 @@ func (app *App) Handler() fasthttp.RequestHandler {
 	return app.handler
 }
 
-// Stack returns the raw router stack.
+// Stack returns a copy of the router stack, the routes can be added and removed while it is used.
 func (app *App) Stack() [][]*Route {
-	return app.stack
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	stack := make([][]*Route, len(app.stack))
+	for m := range app.stack {
+		stack[m] = append([]*Route(nil), app.stack[m]...)
+	}
+	return stack
 }
 
 // HandlersCount returns the amount of registered handlers.
@@ -1017,6 +1212,20 @@ func (app *App) ErrorHandler(req *Request, res *Response, err error) error {
 		mountedPrefixParts int
 	)
 
+	// Every 405 response has an Allow header, also if the handler rejected the method (RFC 9110, 15.5.6)
+	if len(res.ctx.fasthttp.Response.Header.Peek(HeaderAllow)) == 0 {
+		if errorStatus(err) == StatusMethodNotAllowed {
+			values, indexRoute := req.ctx.values, req.ctx.indexRoute
+			methodExist(req.ctx)
+			req.ctx.values, req.ctx.indexRoute = values, indexRoute
+		}
+	}
+
+	// The routes of mounted apps are handled by the error handler of their app
+	if req.ctx.app != req.ctx.router {
+		return req.ctx.app.config.ErrorHandler(req, res, err)
+	}
+
 	for prefix, errHandler := range app.errorHandlers {
 		if strings.HasPrefix(req.Path(), prefix) {
 			parts := len(strings.Split(prefix, "/"))
@@ -1064,8 +1273,9 @@ func (app *App) serverErrorHandler(fctx *fasthttp.RequestCtx, err error) {
 // startupProcess Is the method which executes all the necessary processes just before the start of the server.
 func (app *App) startupProcess() *App {
 	app.mutex.Lock()
+	defer app.mutex.Unlock()
 	app.buildTree()
-	app.mutex.Unlock()
+	app.treeBuilt = true
 	return app
 }
 
@@ -1081,7 +1291,7 @@ func (app *App) startupMessage(addr string, tls bool, pids string) {
 		// cRed   = "\u001b[91m"
 		cCyan = "\u001b[96m"
 		// cGreen = "\u001b[92m"
-		// cYellow  = "\u001b[93m"
+		cYellow = "\u001b[93m"
 		// cBlue    = "\u001b[94m"
 		// cMagenta = "\u001b[95m"
 		// cWhite   = "\u001b[97m"
@@ -1266,6 +1476,13 @@ func (app *App) startupMessage(addr string, tls bool, pids string) {
 		output += cBlack + splitMainLogo[i] + " " + splitChildPidsLogo[i] + "\n"
 	}
 
+	// Add the conflicts of the route stack
+	if app.config.RouteConflictPolicy == RouteConflictWarn {
+		for _, conflict := range app.routeConflicts {
+			output += cYellow + "[Warning] route conflict: " + conflict + cReset + "\n"
+		}
+	}
+
 	out := colorable.NewColorableStdout()
 	if os.Getenv("TERM") == "dumb" || os.Getenv("NO_COLOR") == "1" || (!isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd())) {
 		out = colorable.NewNonColorable(os.Stdout)
@@ -1297,7 +1514,7 @@ func (app *App) printRoutesMessage() {
 		// cReset = "\u001b[0m"
 	)
 	var routes []RouteMessage
-	for _, routeStack := range app.stack {
+	for _, routeStack := range app.Stack() {
 		for _, route := range routeStack {
 			var newRoute = RouteMessage{}
 			newRoute.name = route.Name
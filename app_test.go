@@ -20,6 +20,7 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -92,6 +93,116 @@ func Test_App_MethodNotAllowed(t *testing.T) {
 	utils.AssertEqual(t, "GET, HEAD, POST, OPTIONS", resp.Header.Get(HeaderAllow))
 }
 
+// go test -run Test_App_AutoOptions
+func Test_App_AutoOptions(t *testing.T) {
+	app := New(Config{AutoOptions: true})
+
+	app.Add(MethodGet, "/users/:id", testEmptyHandler)
+	app.Patch("/users/:id", testEmptyHandler)
+	app.Options("/custom", func(req *Request, res *Response) error {
+		return res.Status(StatusOK).String("custom")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodOptions, "/users/1", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNoContent, resp.StatusCode)
+	utils.AssertEqual(t, "GET, PATCH, OPTIONS", resp.Header.Get(HeaderAllow))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "", string(body))
+
+	// Registered OPTIONS routes are preferred
+	resp, err = app.Test(httptest.NewRequest(MethodOptions, "/custom", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderAllow))
+
+	resp, err = app.Test(httptest.NewRequest(MethodOptions, "/unknown", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+
+	// OPTIONS is also part of the Allow header of 405 responses
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/users/1", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "GET, OPTIONS, PATCH", resp.Header.Get(HeaderAllow))
+}
+
+// go test -run Test_App_AutoHead
+func Test_App_AutoHead(t *testing.T) {
+	app := New(Config{AutoHead: true})
+
+	var calls int
+	app.Use(func(req *Request, res *Response) error {
+		calls++
+		return req.Next()
+	})
+	app.Add(MethodGet, "/", func(req *Request, res *Response) error {
+		res.Header.Set("X-Method", req.Method())
+		return res.String("Hello, World!")
+	})
+	app.Head("/custom", func(req *Request, res *Response) error {
+		res.Header.Set("X-Method", "custom")
+		return nil
+	})
+	app.Add(MethodGet, "/custom", testEmptyHandler)
+
+	resp, err := app.Test(httptest.NewRequest(MethodHead, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, MethodHead, resp.Header.Get("X-Method"))
+	utils.AssertEqual(t, 1, calls)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "", string(body))
+
+	// Registered HEAD routes are preferred
+	resp, err = app.Test(httptest.NewRequest(MethodHead, "/custom", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "custom", resp.Header.Get("X-Method"))
+
+	resp, err = app.Test(httptest.NewRequest(MethodHead, "/unknown", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+
+	// HEAD is also part of the Allow header of 405 responses
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "GET, HEAD", resp.Header.Get(HeaderAllow))
+}
+
+// go test -run Test_App_Allow_Header_Handler_Error
+func Test_App_Allow_Header_Handler_Error(t *testing.T) {
+	app := New()
+
+	app.Get("/", testEmptyHandler)
+	app.Post("/", testEmptyHandler)
+	app.Put("/", func(req *Request, res *Response) error {
+		return ErrMethodNotAllowed
+	})
+	app.Delete("/", func(req *Request, res *Response) error {
+		return NewError(StatusMethodNotAllowed, "read only")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodPut, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "GET, HEAD, POST, DELETE", resp.Header.Get(HeaderAllow))
+
+	resp, err = app.Test(httptest.NewRequest(MethodDelete, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "GET, HEAD, POST, PUT", resp.Header.Get(HeaderAllow))
+
+	// The Allow header of the router is not duplicated
+	resp, err = app.Test(httptest.NewRequest(MethodPatch, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "GET, HEAD, POST, PUT, DELETE", resp.Header.Get(HeaderAllow))
+}
+
 func Test_App_Custom_Middleware_404_Should_Not_SetMethodNotAllowed(t *testing.T) {
 	app := New()
 
@@ -303,6 +414,79 @@ func Test_App_Mount(t *testing.T) {
 	utils.AssertEqual(t, uint32(2), app.handlersCount)
 }
 
+// go test -run Test_App_Mount_Config
+func Test_App_Mount_Config(t *testing.T) {
+	deep := New(Config{
+		ErrorHandler: func(req *Request, res *Response, err error) error {
+			return res.Status(StatusTeapot).String("deep: " + err.Error())
+		},
+	})
+	micro := New(Config{
+		BodyLimit: 4,
+		JSONEncoder: func(v interface{}) ([]byte, error) {
+			return []byte(`"micro"`), nil
+		},
+	})
+	app := New()
+
+	deep.Get("/error", func(req *Request, res *Response) error {
+		utils.AssertEqual(t, deep, req.Ctx().App())
+		return errors.New("failed")
+	}).Name("deep.error")
+	micro.Get("/json", func(req *Request, res *Response) error {
+		utils.AssertEqual(t, micro, req.Ctx().App())
+		return res.JSON(Map{"app": "parent"})
+	}).Name("micro.json")
+	micro.Post("/upload", testEmptyHandler)
+	micro.Mount("/deep", deep)
+
+	app.Use(func(req *Request, res *Response) error {
+		utils.AssertEqual(t, app, req.Ctx().App())
+		return req.Next()
+	})
+	app.Get("/json", func(req *Request, res *Response) error {
+		return res.JSON(Map{"app": "parent"})
+	})
+	app.Mount("/micro", micro)
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/micro/json", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `"micro"`, string(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/json", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"app":"parent"}`, string(body))
+
+	// The body limit of the mounted app
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/micro/upload", strings.NewReader("too large")))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, resp.StatusCode, "Status code")
+
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/micro/upload", strings.NewReader("ok")))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	// Nested mounts keep the config of the innermost app
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/micro/deep/error", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusTeapot, resp.StatusCode, "Status code")
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "deep: failed", string(body))
+
+	// Named routes are found across the mounts
+	utils.AssertEqual(t, "/micro/json", app.GetRoute("micro.json").Path)
+	utils.AssertEqual(t, "/micro/deep/error", app.GetRoute("deep.error").Path)
+	url, err := app.URL("deep.error", nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/micro/deep/error", url)
+}
+
 func Test_App_Use_Params(t *testing.T) {
 	app := New()
 
@@ -679,7 +863,7 @@ func Test_App_Static_Group(t *testing.T) {
 	app := New()
 
 	grp := app.Group("/v1", func(req *Request, res *Response) error {
-		req.Header.Set("Test-Header", "123")
+		res.Header.Set("Test-Header", "123")
 		return req.Next()
 	})
 
@@ -870,7 +1054,7 @@ func Test_App_Mixed_Routes_WithSameLen(t *testing.T) {
 
 	// middleware
 	app.Use(func(req *Request, res *Response) error {
-		req.Header.Set("TestHeader", "TestValue")
+		res.Header.Set("TestHeader", "TestValue")
 		return req.Next()
 	})
 	// routes with the same length
@@ -1307,6 +1491,125 @@ func Test_App_Stack(t *testing.T) {
 	utils.AssertEqual(t, 1, len(stack[methodInt(MethodTrace)]))
 }
 
+// go test -run Test_App_RemoveRoute
+func Test_App_RemoveRoute(t *testing.T) {
+	app := New()
+
+	app.Use("/api", func(req *Request, res *Response) error {
+		res.Header.Set("X-Middleware", "api")
+		return req.Next()
+	})
+	app.Get("/api/users/:id", testEmptyHandler)
+	app.Post("/api/users/:id", testEmptyHandler)
+	// The middleware is added to the stack of every method, Get also adds a HEAD route
+	utils.AssertEqual(t, uint32(4), app.HandlersCount())
+	utils.AssertEqual(t, uint32(len(app.config.RequestMethods)+3), app.routesCount)
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/api/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "api", resp.Header.Get("X-Middleware"))
+
+	utils.AssertEqual(t, true, app.RemoveRoute("get", "/api/users/:id"))
+	utils.AssertEqual(t, false, app.RemoveRoute(MethodGet, "/api/users/:id"))
+	utils.AssertEqual(t, false, app.RemoveRoute(MethodGet, "/api/unknown"))
+	utils.AssertEqual(t, uint32(3), app.HandlersCount())
+	utils.AssertEqual(t, uint32(len(app.config.RequestMethods)+2), app.routesCount)
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/api/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "HEAD, POST", resp.Header.Get(HeaderAllow))
+
+	// Remove the middleware of all methods
+	utils.AssertEqual(t, true, app.RemoveRoute(methodUse, "/api"))
+	utils.AssertEqual(t, uint32(2), app.HandlersCount())
+	utils.AssertEqual(t, uint32(2), app.routesCount)
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/api/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "", resp.Header.Get("X-Middleware"))
+
+	for _, routes := range app.Stack() {
+		for _, route := range routes {
+			utils.AssertEqual(t, false, route.use)
+		}
+	}
+}
+
+// go test -run Test_App_RemoveRouteByName
+func Test_App_RemoveRouteByName(t *testing.T) {
+	app := New()
+	app.Add(MethodGet, "/plugin", testEmptyHandler).Name("plugin")
+	app.Add(MethodGet, "/other", testEmptyHandler).Name("other")
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/plugin", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	utils.AssertEqual(t, true, app.RemoveRouteByName("plugin"))
+	utils.AssertEqual(t, false, app.RemoveRouteByName("plugin"))
+	utils.AssertEqual(t, false, app.RemoveRouteByName(""))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/plugin", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "other", app.GetRoute("other").Name)
+}
+
+// go test -run Test_App_Routes_AfterStartup
+func Test_App_Routes_AfterStartup(t *testing.T) {
+	app := New()
+	handler := app.Handler()
+
+	serve := func(path string) int {
+		fctx := &fasthttp.RequestCtx{}
+		fctx.Request.Header.SetMethod(MethodGet)
+		fctx.URI().SetPath(path)
+		handler(fctx)
+		return fctx.Response.StatusCode()
+	}
+
+	// The routes are published without another startup
+	app.Get("/plugin", testEmptyHandler).Name("plugin")
+	utils.AssertEqual(t, StatusOK, serve("/plugin"))
+
+	// A running request keeps the routes it started with
+	app.Use("/remove", func(req *Request, res *Response) error {
+		app.RemoveRoute(MethodGet, "/remove")
+		return req.Next()
+	})
+	app.Add(MethodGet, "/remove", func(req *Request, res *Response) error {
+		return res.Status(StatusAccepted).Send()
+	})
+	utils.AssertEqual(t, StatusAccepted, serve("/remove"))
+	utils.AssertEqual(t, StatusNotFound, serve("/remove"))
+
+	// Requests and route changes at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				path := fmt.Sprintf("/concurrent/%d/%d", i, n)
+				app.Get(path, testEmptyHandler).Name(path)
+				app.RemoveRoute(MethodGet, path)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				utils.AssertEqual(t, StatusOK, serve("/plugin"))
+				url, err := app.URL("plugin", nil)
+				utils.AssertEqual(t, nil, err)
+				utils.AssertEqual(t, "/plugin", url)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // go test -run Test_App_HandlersCount
 func Test_App_HandlersCount(t *testing.T) {
 	app := New()
@@ -1670,3 +1973,128 @@ func Test_App_print_Route_with_group(t *testing.T) {
 	utils.AssertEqual(t, true, strings.Contains(printRoutesMessage, "PUT"))
 	utils.AssertEqual(t, true, strings.Contains(printRoutesMessage, "/v1/test/fiber/*"))
 }
+
+// go test -run Test_App_RequestMethods
+func Test_App_RequestMethods(t *testing.T) {
+	app := New(Config{
+		RequestMethods: append(DefaultMethods(), "purge", "PROPFIND", "PURGE"),
+	})
+	utils.AssertEqual(t, append(DefaultMethods(), "PURGE", "PROPFIND"), app.config.RequestMethods)
+
+	app.Add("PURGE", "/cache/:key", func(req *Request, res *Response) error {
+		return res.String("purged " + req.Param("key"))
+	})
+	app.All("/dav", func(req *Request, res *Response) error {
+		return res.String(req.Method())
+	})
+	app.Group("/api").Add("PROPFIND", "/files", testEmptyHandler)
+
+	resp, err := app.Test(httptest.NewRequest("PURGE", "/cache/users", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "purged users", string(body))
+
+	resp, err = app.Test(httptest.NewRequest("PROPFIND", "/dav", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "PROPFIND", string(body))
+
+	resp, err = app.Test(httptest.NewRequest("PROPFIND", "/api/files", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	// Custom methods are part of the Allow header
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/cache/users", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "PURGE", resp.Header.Get(HeaderAllow))
+
+	// Unknown methods are still rejected
+	resp, err = app.Test(httptest.NewRequest("MKCOL", "/dav", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusBadRequest, resp.StatusCode)
+
+	printRoutesMessage := captureOutput(func() {
+		app.printRoutesMessage()
+	})
+	utils.AssertEqual(t, true, strings.Contains(printRoutesMessage, "PURGE"))
+	utils.AssertEqual(t, true, strings.Contains(printRoutesMessage, "PROPFIND"))
+
+	defer func() {
+		if err := recover(); err != nil {
+			utils.AssertEqual(t, "add: invalid http method MKCOL\n", fmt.Sprintf("%v", err))
+		}
+	}()
+	app.Add("MKCOL", "/dav", testEmptyHandler)
+}
+
+// go test -run Test_App_RequestMethods_Mount
+func Test_App_RequestMethods_Mount(t *testing.T) {
+	// The middlewares of the methods which the parent doesn't accept are skipped
+	sub := New(Config{RequestMethods: append(DefaultMethods(), "PURGE")})
+	sub.Use(func(req *Request, res *Response) error {
+		res.Header.Set("X-Sub", "1")
+		return req.Next()
+	})
+	sub.Get("/users", testEmptyHandler)
+
+	app := New()
+	app.Mount("/api", sub)
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/api/users", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "1", resp.Header.Get("X-Sub"))
+
+	// The routes of the methods panic
+	sub.Add("PURGE", "/cache", testEmptyHandler)
+	defer func() {
+		utils.AssertEqual(t, "mount: method PURGE of the route /cache is not in the RequestMethods of the app\n", fmt.Sprintf("%v", recover()))
+	}()
+	New().Group("/v1").Mount("/api", sub)
+}
+
+// go test -run Test_App_DefaultMethods
+func Test_App_DefaultMethods(t *testing.T) {
+	// The default methods can't be changed through the returned slice
+	methods := DefaultMethods()
+	methods[0] = "PURGE"
+	utils.AssertEqual(t, MethodGet, DefaultMethods()[0])
+
+	app := New()
+	app.Get("/", testEmptyHandler)
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+}
+
+// go test -run Test_App_RequestMethods_Custom
+func Test_App_RequestMethods_Custom(t *testing.T) {
+	// Only the configured methods are accepted, the default methods are not added
+	app := New(Config{RequestMethods: []string{MethodGet, "LINK"}})
+	app.Add("LINK", "/", testEmptyHandler)
+	app.Add(MethodGet, "/", testEmptyHandler)
+
+	resp, err := app.Test(httptest.NewRequest("LINK", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusBadRequest, resp.StatusCode)
+	methods := make([]string, maxMethods+1)
+	for i := range methods {
+		methods[i] = "METHOD" + strconv.Itoa(i)
+	}
+	defer func() {
+		utils.AssertEqual(t, "route: at most 64 request methods are supported\n", fmt.Sprintf("%v", recover()))
+	}()
+	New(Config{RequestMethods: methods})
+}
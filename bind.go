@@ -0,0 +1,351 @@
+package lightning
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// Some constants for Bind
+const (
+	paramTag    = "param"
+	cookieTag   = "cookie"
+	validateTag = "validate"
+)
+
+// Bind binds the route params, the query, the headers, the cookies and the body of the request
+// to a struct and validates the result with the validate tags of its fields. The body is decoded
+// like BodyParser based on the Content-Type header. An empty body is only decoded for POST, PUT and PATCH
+// requests with a Content-Type, other methods like GET and DELETE skip it even if a Content-Type is set.
+// The route params are bound last, so they win over a query, body or header value of the same field.
+//  type User struct {
+//  	ID    int    `param:"id" validate:"required,min=1"`
+//  	Page  int    `query:"page"`
+//  	Token string `reqHeader:"X-Token"`
+//  	Theme string `cookie:"theme" validate:"omitempty,oneof=dark light"`
+//  	Email string `json:"email" validate:"required,email"`
+//  }
+// Invalid values are reported by a *ValidationError which lists all failing fields,
+// unknown rules or invalid rule parameters in the validate tags are reported as an error.
+func (req *Request) Bind(out interface{}) error {
+	c := req.ctx
+	if t := reflect.TypeOf(out); t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: out must be a pointer to a struct")
+	}
+	// Invalid validate tags are reported before anything is bound
+	if _, err := structRules(reflect.TypeOf(out).Elem()); err != nil {
+		return err
+	}
+	if err := c.QueryParser(out); err != nil {
+		return err
+	}
+	if err := c.ReqHeaderParser(out); err != nil {
+		return err
+	}
+	// Cookies
+	data := make(map[string][]string)
+	c.fasthttp.Request.Header.VisitAllCookie(func(key, val []byte) {
+		k := utils.UnsafeString(key)
+		data[k] = append(data[k], utils.UnsafeString(val))
+	})
+	if len(data) > 0 {
+		if err := c.parseToStruct(cookieTag, out, data); err != nil {
+			return err
+		}
+	}
+	// Body, the methods without a request body ignore the Content-Type of an empty body
+	if len(c.fasthttp.Request.Body()) > 0 || (len(c.fasthttp.Request.Header.ContentType()) > 0 && hasRequestBody(c.method)) {
+		if err := c.BodyParser(out); err != nil {
+			return err
+		}
+	}
+	// Route params are bound last, they are part of the matched route and can't be overwritten
+	if c.route != nil && len(c.route.Params) > 0 {
+		data = make(map[string][]string, len(c.route.Params))
+		for _, key := range c.route.Params {
+			data[key] = []string{c.Params(key)}
+		}
+		if err := c.parseToStruct(paramTag, out, data); err != nil {
+			return err
+		}
+	}
+	return validateStruct(reflect.ValueOf(out).Elem())
+}
+
+// hasRequestBody reports whether the request body is part of the semantics of the method
+func hasRequestBody(method string) bool {
+	switch method {
+	case MethodPost, MethodPut, MethodPatch:
+		return true
+	}
+	return false
+}
+
+// FieldError describes a field which violates a rule of its validate tag
+type FieldError struct {
+	Field string `json:"field"`           // Name of the field, nested fields are separated by dots
+	Rule  string `json:"rule"`            // Violated rule, e.g. "min"
+	Param string `json:"param,omitempty"` // Parameter of the rule, e.g. "1"
+}
+
+// Error returns a readable description of the violated rule
+func (e FieldError) Error() string {
+	switch e.Rule {
+	case "required":
+		return e.Field + " is required"
+	case "min":
+		return e.Field + " must be at least " + e.Param
+	case "max":
+		return e.Field + " must be at most " + e.Param
+	case "email":
+		return e.Field + " must be a valid email address"
+	case "oneof":
+		return e.Field + " must be one of " + e.Param
+	}
+	return e.Field + " violates " + e.Rule
+}
+
+// ValidationError is returned by Bind if fields violate the rules of their validate tags
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error returns the descriptions of all failing fields
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i := range e.Fields {
+		messages[i] = e.Fields[i].Error()
+	}
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+// fieldRules holds the parsed validate tag of a struct field
+type fieldRules struct {
+	index     int            // index of the field in the struct
+	name      string         // name of the field in the request
+	anonymous bool           // embedded structs are validated without a name prefix
+	rules     []validateRule // parsed rules of the validate tag
+}
+
+// validateRule is a single parsed rule of a validate tag, e.g. "min=1"
+type validateRule struct {
+	name    string   // name of the rule, e.g. "min"
+	param   string   // raw parameter of the rule, e.g. "1"
+	limit   float64  // parameter of the min and max rules
+	options []string // parameter of the oneof rule
+}
+
+// validateRules caches the parsed validate tags of the struct types
+var validateRules sync.Map
+
+// structRules returns the parsed validate tags of the struct type, the tags of the type and its nested
+// struct types are parsed when the type is seen the first time, so invalid tags never reach the validation
+func structRules(t reflect.Type) ([]fieldRules, error) {
+	if cached, ok := validateRules.Load(t); ok {
+		return cached.([]fieldRules), nil
+	}
+	return parseStructRules(t, make(map[reflect.Type]bool))
+}
+
+// parseStructRules parses the validate tags of the struct type and its nested struct types and caches them
+func parseStructRules(t reflect.Type, seen map[reflect.Type]bool) ([]fieldRules, error) {
+	if cached, ok := validateRules.Load(t); ok {
+		return cached.([]fieldRules), nil
+	}
+	// Recursive types are parsed once
+	if seen[t] {
+		return nil, nil
+	}
+	seen[t] = true
+
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// Unexported fields can't be bound
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		rules := fieldRules{index: i, name: fieldName(field), anonymous: field.Anonymous}
+		if tag := field.Tag.Get(validateTag); tag != "" && tag != "-" {
+			for _, rule := range strings.Split(tag, ",") {
+				parsed, err := parseValidateRule(rule, field.Name)
+				if err != nil {
+					return nil, err
+				}
+				rules.rules = append(rules.rules, parsed)
+			}
+		}
+		fields = append(fields, rules)
+
+		// Nested structs and slices of structs
+		nested := field.Type
+		for nested.Kind() == reflect.Ptr || nested.Kind() == reflect.Slice || nested.Kind() == reflect.Array {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct {
+			if _, err := parseStructRules(nested, seen); err != nil {
+				return nil, err
+			}
+		}
+	}
+	validateRules.Store(t, fields)
+	return fields, nil
+}
+
+// parseValidateRule parses a rule of a validate tag, e.g. "required", "min=1" or "oneof=a b"
+func parseValidateRule(rule, field string) (validateRule, error) {
+	parsed := validateRule{name: rule}
+	if i := strings.IndexByte(rule, '='); i != -1 {
+		parsed.name, parsed.param = rule[:i], rule[i+1:]
+	}
+	switch parsed.name {
+	case "omitempty", "required", "email":
+	case "min", "max":
+		limit, err := strconv.ParseFloat(parsed.param, 64)
+		if err != nil {
+			return parsed, fmt.Errorf("bind: invalid limit %q of the validate rule %s of %s", parsed.param, parsed.name, field)
+		}
+		parsed.limit = limit
+	case "oneof":
+		parsed.options = strings.Fields(parsed.param)
+	default:
+		return parsed, fmt.Errorf("bind: unknown validate rule %s of %s", parsed.name, field)
+	}
+	return parsed, nil
+}
+
+// validateStruct checks the validate tags of the struct and its nested structs
+func validateStruct(v reflect.Value) error {
+	if _, err := structRules(v.Type()); err != nil {
+		return err
+	}
+	var fields []FieldError
+	validateFields(v, "", &fields)
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// validateFields appends the failing fields of the struct to the list
+func validateFields(v reflect.Value, prefix string, fields *[]FieldError) {
+	// The rules of the nested types are cached by validateStruct
+	rules, _ := structRules(v.Type())
+	for _, field := range rules {
+		name := prefix + field.name
+		value := v.Field(field.index)
+		if len(field.rules) > 0 {
+			validateValue(value, name, field.rules, fields)
+		}
+		// Validate nested structs and slices of structs
+		for value.Kind() == reflect.Ptr && !value.IsNil() {
+			value = value.Elem()
+		}
+		switch {
+		case value.Kind() == reflect.Struct:
+			if field.anonymous {
+				validateFields(value, prefix, fields)
+			} else {
+				validateFields(value, name+".", fields)
+			}
+		case value.Kind() == reflect.Slice || value.Kind() == reflect.Array:
+			for j := 0; j < value.Len(); j++ {
+				elem := value.Index(j)
+				for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+					elem = elem.Elem()
+				}
+				if elem.Kind() == reflect.Struct {
+					validateFields(elem, name+"["+strconv.Itoa(j)+"].", fields)
+				}
+			}
+		}
+	}
+}
+
+// validateValue checks the parsed rules of the validate tag, e.g. "required,min=1,max=64,email,oneof=a b"
+func validateValue(value reflect.Value, name string, rules []validateRule, fields *[]FieldError) {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			// Only the required rule applies to missing values
+			for _, rule := range rules {
+				if rule.name == "required" {
+					*fields = append(*fields, FieldError{Field: name, Rule: rule.name})
+				}
+			}
+			return
+		}
+		value = value.Elem()
+	}
+	for _, rule := range rules {
+		valid := true
+		switch rule.name {
+		case "omitempty":
+			if value.IsZero() {
+				return
+			}
+		case "required":
+			valid = !value.IsZero()
+		case "min", "max":
+			valid = validateLimit(value, rule.name == "min", rule.limit)
+		case "email":
+			address, err := mail.ParseAddress(value.String())
+			valid = value.Kind() == reflect.String && err == nil && address.Address == value.String()
+		case "oneof":
+			valid = false
+			for _, option := range rule.options {
+				if fmt.Sprint(value) == option {
+					valid = true
+					break
+				}
+			}
+		}
+		if !valid {
+			*fields = append(*fields, FieldError{Field: name, Rule: rule.name, Param: rule.param})
+			// Report only the first violated rule of a field
+			return
+		}
+	}
+}
+
+// validateLimit compares numbers by their value and strings, slices and maps by their length
+func validateLimit(value reflect.Value, isMin bool, limit float64) bool {
+	var n float64
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	case reflect.String:
+		n = float64(len([]rune(value.String())))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n = float64(value.Len())
+	default:
+		return false
+	}
+	if isMin {
+		return n >= limit
+	}
+	return n <= limit
+}
+
+// fieldName returns the name of the field in the request, the first tag of json, form, query,
+// param, reqHeader and cookie is used before the name of the field
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", bodyTag, queryTag, paramTag, reqHeaderTag, cookieTag} {
+		tag := field.Tag.Get(key)
+		if i := strings.IndexByte(tag, ','); i != -1 {
+			tag = tag[:i]
+		}
+		if tag != "" && tag != "-" {
+			return tag
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,243 @@
+package lightning
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp"
+)
+
+type bindAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type bindUser struct {
+	ID        int           `param:"id" validate:"required,min=1"`
+	Page      int           `query:"page" validate:"max=100"`
+	Tags      []string      `query:"tags"`
+	Token     string        `reqHeader:"X-Token" validate:"required"`
+	Theme     string        `cookie:"theme" validate:"omitempty,oneof=dark light"`
+	Name      string        `json:"name" form:"name" validate:"required,min=2,max=64"`
+	Email     string        `json:"email" form:"email" validate:"omitempty,email"`
+	Nickname  *string       `json:"nickname" validate:"min=3"`
+	Address   *bindAddress  `json:"address"`
+	Addresses []bindAddress `json:"addresses" validate:"max=2"`
+}
+
+// go test -run Test_Request_Bind
+func Test_Request_Bind(t *testing.T) {
+	t.Parallel()
+	app := New()
+	var user bindUser
+	app.Post("/users/:id", func(req *Request, res *Response) error {
+		user = bindUser{}
+		return req.Bind(&user)
+	})
+
+	req := httptest.NewRequest(MethodPost, "/users/42?page=2&tags=a,b", strings.NewReader(
+		`{"name":"john","email":"john@example.com","nickname":"johnny","address":{"city":"Berlin"}}`,
+	))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	req.Header.Set("X-Token", "secret")
+	req.Header.Set(HeaderCookie, "theme=dark; other=1")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	b, _ := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, string(b))
+
+	nickname := "johnny"
+	utils.AssertEqual(t, bindUser{
+		ID:       42,
+		Page:     2,
+		Tags:     []string{"a", "b"},
+		Token:    "secret",
+		Theme:    "dark",
+		Name:     "john",
+		Email:    "john@example.com",
+		Nickname: &nickname,
+		Address:  &bindAddress{City: "Berlin"},
+	}, user)
+
+	// Form bodies
+	req = httptest.NewRequest(MethodPost, "/users/1", strings.NewReader("name=jane&email=jane@example.com"))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	req.Header.Set("X-Token", "secret")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "jane", user.Name)
+	utils.AssertEqual(t, "jane@example.com", user.Email)
+
+	// Unsupported bodies
+	req = httptest.NewRequest(MethodPost, "/users/1", strings.NewReader("john"))
+	req.Header.Set(HeaderContentType, MIMETextPlain)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusUnprocessableEntity, resp.StatusCode)
+}
+
+// go test -run Test_Request_Bind_Validation
+func Test_Request_Bind_Validation(t *testing.T) {
+	t.Parallel()
+	app := New()
+	var bindErr error
+	app.Post("/users/:id", func(req *Request, res *Response) error {
+		bindErr = req.Bind(&bindUser{})
+		return bindErr
+	})
+
+	req := httptest.NewRequest(MethodPost, "/users/0?page=101", strings.NewReader(
+		`{"name":"j","email":"john","nickname":"jo","address":{},"addresses":[{"city":"Rome"},{}]}`,
+	))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	req.Header.Set(HeaderCookie, "theme=blue")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusBadRequest, resp.StatusCode)
+
+	validationErr, ok := bindErr.(*ValidationError)
+	utils.AssertEqual(t, true, ok)
+	utils.AssertEqual(t, []FieldError{
+		{Field: "id", Rule: "required"},
+		{Field: "page", Rule: "max", Param: "100"},
+		{Field: "X-Token", Rule: "required"},
+		{Field: "theme", Rule: "oneof", Param: "dark light"},
+		{Field: "name", Rule: "min", Param: "2"},
+		{Field: "email", Rule: "email"},
+		{Field: "nickname", Rule: "min", Param: "3"},
+		{Field: "address.city", Rule: "required"},
+		{Field: "addresses[1].city", Rule: "required"},
+	}, validationErr.Fields)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "validation failed: id is required, page must be at most 100, X-Token is required, "+
+		"theme must be one of dark light, name must be at least 2, email must be a valid email address, "+
+		"nickname must be at least 3, address.city is required, addresses[1].city is required", string(body))
+}
+
+// go test -run Test_Request_Bind_Invalid
+func Test_Request_Bind_Invalid(t *testing.T) {
+	t.Parallel()
+	app := New()
+	req, _ := app.AcquireReqRes(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(req.ctx)
+
+	var s string
+	utils.AssertEqual(t, "bind: out must be a pointer to a struct", req.Bind(&s).Error())
+	utils.AssertEqual(t, "bind: out must be a pointer to a struct", req.Bind(bindUser{}).Error())
+	utils.AssertEqual(t, "bind: out must be a pointer to a struct", req.Bind(nil).Error())
+}
+
+// go test -run Test_ValidateStruct
+func Test_ValidateStruct(t *testing.T) {
+	t.Parallel()
+	type rules struct {
+		Required []int            `validate:"required"`
+		Min      float64          `validate:"min=1.5"`
+		Max      map[string]int   `validate:"max=1"`
+		Unicode  string           `validate:"max=2"`
+		OneOf    int              `validate:"oneof=1 2 3"`
+		Email    string           `validate:"email"`
+		Nested   struct{ A uint } `validate:"-"`
+	}
+	err := validateStruct(reflect.ValueOf(rules{
+		Required: []int{1},
+		Min:      1.5,
+		Max:      map[string]int{"a": 1},
+		Unicode:  "äö",
+		OneOf:    3,
+		Email:    "Jane Doe <jane@example.com>",
+	}))
+	utils.AssertEqual(t, []FieldError{{Field: "Email", Rule: "email"}}, err.(*ValidationError).Fields)
+
+	err = validateStruct(reflect.ValueOf(struct {
+		ID string `validate:"uuid"`
+	}{}))
+	utils.AssertEqual(t, "bind: unknown validate rule uuid of ID", err.Error())
+}
+
+// go test -run Test_Request_Bind_Order
+func Test_Request_Bind_Order(t *testing.T) {
+	t.Parallel()
+	type order struct {
+		ID int `param:"id" query:"id" reqHeader:"X-Id" json:"id"`
+	}
+	app := New()
+	var out order
+	app.Post("/orders/:id", func(req *Request, res *Response) error {
+		out = order{}
+		return req.Bind(&out)
+	})
+
+	// The route param wins over the query, the header and the body
+	req := httptest.NewRequest(MethodPost, "/orders/3?id=1", strings.NewReader(`{"id":2}`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	req.Header.Set("X-Id", "4")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, 3, out.ID)
+}
+
+// go test -run Test_Request_Bind_EmptyBody
+func Test_Request_Bind_EmptyBody(t *testing.T) {
+	t.Parallel()
+	type search struct {
+		ID   int    `param:"id"`
+		Term string `query:"term" validate:"required"`
+	}
+	app := New()
+	var out search
+	handler := func(req *Request, res *Response) error {
+		out = search{}
+		return req.Bind(&out)
+	}
+	app.Get("/items/:id", handler)
+	app.Delete("/items/:id", handler)
+	app.Post("/items/:id", handler)
+
+	// A Content-Type without a body is ignored for GET and DELETE
+	for _, method := range []string{MethodGet, MethodDelete} {
+		req := httptest.NewRequest(method, "/items/7?term=lamp", nil)
+		req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, method)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, method)
+		utils.AssertEqual(t, search{ID: 7, Term: "lamp"}, out, method)
+	}
+
+	// POST expects the announced body
+	req := httptest.NewRequest(MethodPost, "/items/7?term=lamp", nil)
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusInternalServerError, resp.StatusCode)
+}
+
+// go test -run Test_Request_Bind_InvalidRules
+func Test_Request_Bind_InvalidRules(t *testing.T) {
+	t.Parallel()
+	type unknownRule struct {
+		Address struct {
+			Zip string `query:"zip" validate:"uuid"`
+		}
+	}
+	type invalidLimit struct {
+		Page int `query:"page" validate:"min=one"`
+	}
+	app := New()
+	req, _ := app.AcquireReqRes(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(req.ctx)
+	req.ctx.fasthttp.Request.SetRequestURI("/?page=2")
+
+	// The rules of nested structs are checked up front, even without a value
+	utils.AssertEqual(t, "bind: unknown validate rule uuid of Zip", req.Bind(&unknownRule{}).Error())
+	var limit invalidLimit
+	utils.AssertEqual(t, `bind: invalid limit "one" of the validate rule min of Page`, req.Bind(&limit).Error())
+	utils.AssertEqual(t, 0, limit.Page)
+}
@@ -0,0 +1,171 @@
+package lightning
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// RouteConflictPolicy defines how conflicts in the route stack are reported when the tree is built
+type RouteConflictPolicy uint8
+
+const (
+	// RouteConflictIgnore skips the analysis of the route stack
+	RouteConflictIgnore RouteConflictPolicy = iota
+	// RouteConflictWarn prints the conflicts with the startup message
+	RouteConflictWarn
+	// RouteConflictPanic panics if the route stack contains conflicts
+	RouteConflictPanic
+)
+
+// probeValues are used as parameter values to find the paths which are matched by a route
+var probeValues = []string{"0", "1337", "probe", "lightning"}
+
+// analyseRoutes reports exact duplicates, routes which are shadowed by earlier non middleware routes
+// and parameters with different names at the same position
+func (app *App) analyseRoutes(stack [][]*Route, treeStack []*routeTree) (conflicts []string) {
+	var candidates []*Route
+	var values [maxParams]string
+	for m := range stack {
+		for _, route := range stack[m] {
+			if route.use {
+				continue
+			}
+			probes := route.probePaths(&values)
+			if len(probes) == 0 {
+				continue
+			}
+			// only the earlier routes of the tree can shadow the route
+			candidates = treeStack[m].lookup(probes[0], candidates[:0])
+			for _, other := range candidates {
+				if other.pos >= route.pos {
+					break
+				}
+				if other.use || (other.host != nil && other.Host != route.Host) || (other.Version != "" && other.Version != route.Version) {
+					continue
+				}
+				if other.path == route.path && other.Host == route.Host && other.Version == route.Version {
+					conflicts = append(conflicts, fmt.Sprintf("%s %s is registered twice", route.Method, route.routeName()))
+					break
+				}
+				if other.matchAll(probes, &values) {
+					conflicts = append(conflicts, fmt.Sprintf("%s %s is shadowed by %s %s", route.Method, route.routeName(), other.Method, other.routeName()))
+					break
+				}
+			}
+		}
+	}
+	return append(conflicts, app.paramClashes(stack)...)
+}
+
+// paramClashes reports parameters with different names at the same position of the routes,
+// e.g. "/users/:id" and "/users/:userId/posts", and parameters which are used twice in a route
+func (app *App) paramClashes(stack [][]*Route) (conflicts []string) {
+	type param struct {
+		name  string
+		route *Route
+	}
+	params := make(map[string]param)
+	reported := make(map[string]bool)
+	report := func(conflict string) {
+		if !reported[conflict] {
+			reported[conflict] = true
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	for m := range stack {
+		for _, route := range stack[m] {
+			var sb strings.Builder
+			sb.WriteString(route.Host)
+			names := make(map[string]bool, len(route.rawParser.params))
+			for _, segment := range route.rawParser.segs {
+				if !segment.IsParam {
+					sb.WriteString(segment.Const)
+					continue
+				}
+				if segment.IsGreedy {
+					sb.WriteByte(wildcardParam)
+					continue
+				}
+				if names[segment.ParamName] {
+					report(fmt.Sprintf("param :%s is used twice in %s", segment.ParamName, route.routeName()))
+				}
+				names[segment.ParamName] = true
+
+				key := sb.String()
+				if !app.config.CaseSensitive {
+					key = utils.ToLower(key)
+				}
+				if prev, ok := params[key]; !ok {
+					params[key] = param{name: segment.ParamName, route: route}
+				} else if prev.name != segment.ParamName {
+					report(fmt.Sprintf("param :%s in %s clashes with :%s in %s", segment.ParamName, route.routeName(), prev.name, prev.route.routeName()))
+				}
+				sb.WriteByte(paramStarterChar)
+			}
+		}
+	}
+	return
+}
+
+// probePaths creates sample paths which are matched by the route,
+// every parameter gets the same probe value and optional parameters are also left out
+func (r *Route) probePaths(values *[maxParams]string) (probes []string) {
+	if r.star {
+		return []string{"/", "/" + probeValues[0]}
+	}
+	if len(r.Params) == 0 && len(r.routeParser.variants) == 0 {
+		return []string{r.path}
+	}
+	parsers := []routeParser{r.routeParser}
+	if len(r.routeParser.variants) > 0 {
+		parsers = parsers[:0]
+		for _, variant := range r.routeParser.variants {
+			parsers = append(parsers, variant.parser)
+		}
+	}
+	for _, parser := range parsers {
+		for i := -1; i < len(probeValues); i++ {
+			var sb strings.Builder
+			for _, segment := range parser.segs {
+				if !segment.IsParam {
+					sb.WriteString(segment.Const)
+				} else if i >= 0 {
+					sb.WriteString(probeValues[i])
+					if segment.IsGreedy {
+						sb.WriteString("/" + probeValues[i])
+					}
+				} else if !segment.IsOptional {
+					// the empty probe is only possible for optional parameters
+					sb.Reset()
+					break
+				}
+			}
+			probe := sb.String()
+			// the probe must fulfill the constraints of the route itself
+			if probe != "" && r.match(probe, probe, values) {
+				probes = append(probes, probe)
+			}
+		}
+	}
+	return
+}
+
+// matchAll checks if the route matches all paths
+func (r *Route) matchAll(paths []string, values *[maxParams]string) bool {
+	for _, path := range paths {
+		if !r.match(path, path, values) {
+			return false
+		}
+	}
+	return true
+}
+
+// routeName returns the host and the path of the route for the reports
+func (r *Route) routeName() string {
+	if r.Host != "" {
+		return r.Host + r.Path
+	}
+	return r.Path
+}
@@ -0,0 +1,125 @@
+package lightning
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// go test -run Test_App_RouteConflicts
+func Test_App_RouteConflicts(t *testing.T) {
+	t.Parallel()
+	app := New(Config{RouteConflictPolicy: RouteConflictWarn})
+
+	app.Use("/users", testEmptyHandler)
+	app.Add(MethodGet, "/users/:id", testEmptyHandler)
+	app.Add(MethodGet, "/users/me", testEmptyHandler)
+	app.Add(MethodGet, "/users/:userId/posts", testEmptyHandler)
+	app.Add(MethodGet, "/about", testEmptyHandler)
+	app.Add(MethodGet, "/contact", testEmptyHandler)
+	app.Add(MethodGet, "/about", testEmptyHandler)
+	app.Add(MethodGet, "/items/:id<int>", testEmptyHandler)
+	app.Add(MethodGet, "/items/new", testEmptyHandler)
+	app.Add(MethodGet, "/items/5", testEmptyHandler)
+	app.Add(MethodGet, "/files/*", testEmptyHandler)
+	app.Add(MethodGet, "/files/:name", testEmptyHandler)
+	app.Add(MethodGet, "/docs/:lang?", testEmptyHandler)
+	app.Add(MethodGet, "/docs(/:lang)?", testEmptyHandler)
+	app.Add(MethodGet, "/copy/:src/:src", testEmptyHandler)
+	app.Host("admin.example.com").Add(MethodGet, "/about", testEmptyHandler)
+	app.Post("/users/me", testEmptyHandler)
+
+	app.startupProcess()
+
+	utils.AssertEqual(t, []string{
+		"GET /users/me is shadowed by GET /users/:id",
+		"GET /about is registered twice",
+		"GET /items/5 is shadowed by GET /items/:id<int>",
+		"GET /files/:name is shadowed by GET /files/*",
+		"GET /docs(/:lang)? is shadowed by GET /docs/:lang?",
+		"GET admin.example.com/about is shadowed by GET /about",
+		"param :userId in /users/:userId/posts clashes with :id in /users/:id",
+		"param :src is used twice in /copy/:src/:src",
+	}, app.routeConflicts)
+
+	startupMessage := captureOutput(func() {
+		app.startupMessage(":3000", false, "")
+	})
+	utils.AssertEqual(t, true, strings.Contains(startupMessage, "[Warning] route conflict: GET /users/me is shadowed by GET /users/:id"))
+
+	// Routes which are added later are analysed again
+	app.Add(MethodGet, "/contact", testEmptyHandler)
+	utils.AssertEqual(t, "GET /contact is registered twice", app.routeConflicts[6])
+}
+
+// go test -run Test_App_RouteConflicts_Ignore
+func Test_App_RouteConflicts_Ignore(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/users/:id", testEmptyHandler)
+	app.Get("/users/me", testEmptyHandler)
+	app.startupProcess()
+
+	utils.AssertEqual(t, 0, len(app.routeConflicts))
+}
+
+// go test -run Test_App_RouteConflicts_Panic
+func Test_App_RouteConflicts_Panic(t *testing.T) {
+	t.Parallel()
+	app := New(Config{RouteConflictPolicy: RouteConflictPanic})
+	app.Get("/users/:id", testEmptyHandler)
+	app.Get("/users/me", testEmptyHandler)
+
+	defer func() {
+		utils.AssertEqual(t, "route: conflicts in the route stack\n"+
+			"GET /users/me is shadowed by GET /users/:id\n"+
+			"HEAD /users/me is shadowed by HEAD /users/:id\n", fmt.Sprintf("%v", recover()))
+	}()
+	app.startupProcess()
+}
+
+// go test -run Test_App_RouteConflicts_Runtime
+func Test_App_RouteConflicts_Runtime(t *testing.T) {
+	t.Parallel()
+	app := New(Config{RouteConflictPolicy: RouteConflictPanic})
+	app.Add(MethodGet, "/users/:id", testEmptyHandler)
+	app.startupProcess()
+
+	// The conflicting route is rejected before the stack is changed
+	err := app.AddRoute(MethodGet, "/users/me", func(req *Request, res *Response) error {
+		return res.String("me")
+	})
+	utils.AssertEqual(t, "route: conflicts in the route stack\nGET /users/me is shadowed by GET /users/:id", err.Error())
+	utils.AssertEqual(t, 1, len(app.Stack()[app.methodInt(MethodGet)]))
+	utils.AssertEqual(t, uint32(1), app.HandlersCount())
+	utils.AssertEqual(t, uint32(1), app.routesCount)
+
+	utils.AssertEqual(t, nil, app.AddRoute(MethodGet, "/posts/:id", testEmptyHandler))
+	utils.AssertEqual(t, 2, len(app.Stack()[app.methodInt(MethodGet)]))
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/posts/1", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	// Add panics, but also leaves the stack unchanged
+	defer func() {
+		utils.AssertEqual(t, "route: conflicts in the route stack\nGET /posts/new is shadowed by GET /posts/:id\n", fmt.Sprintf("%v", recover()))
+		utils.AssertEqual(t, 2, len(app.Stack()[app.methodInt(MethodGet)]))
+	}()
+	app.Add(MethodGet, "/posts/new", testEmptyHandler)
+}
+
+// go test -v -run=^$ -bench=Benchmark_App_AnalyseRoutes -benchmem -count=4
+func Benchmark_App_AnalyseRoutes(b *testing.B) {
+	app := New()
+	registerDummyRoutes(app)
+	app.startupProcess()
+	treeStack := app.treeStack.Load().([]*routeTree)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		app.routeConflicts = app.analyseRoutes(app.stack, treeStack)
+	}
+}
@@ -1,18 +1,74 @@
 package lightning
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
 	"time"
 )
 
 // Cookie data for c.Cookie
 type Cookie struct {
-	Name     string    `json:"name"`
-	Value    string    `json:"value"`
-	Path     string    `json:"path"`
-	Domain   string    `json:"domain"`
-	MaxAge   int       `json:"max_age"`
-	Expires  time.Time `json:"expires"`
-	Secure   bool      `json:"secure"`
-	HTTPOnly bool      `json:"http_only"`
-	SameSite string    `json:"same_site"`
+	Name        string    `json:"name"`
+	Value       string    `json:"value"`
+	Path        string    `json:"path"`
+	Domain      string    `json:"domain"`
+	MaxAge      int       `json:"max_age"`
+	Expires     time.Time `json:"expires"`
+	Secure      bool      `json:"secure"`
+	HTTPOnly    bool      `json:"http_only"`
+	SameSite    string    `json:"same_site"`
+	Partitioned bool      `json:"partitioned"` // CHIPS, a partitioned cookie is always secure
+	Priority    string    `json:"priority"`    // low, medium or high, not set if empty
+}
+
+var (
+	ErrCookieNotFound      = errors.New("cookie: named cookie not present")
+	ErrCookieSignature     = errors.New("cookie: invalid signature")
+	ErrCookieNoSigningKeys = errors.New("cookie: no signing keys configured")
+)
+
+// cookieSignature returns the HMAC-SHA256 of the name and the value, the name is signed
+// so that a signed value can't be moved to another cookie.
+func cookieSignature(key []byte, name, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(name))
+	_, _ = mac.Write([]byte{'='})
+	_, _ = mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// signCookieValue appends the signature of the newest key to the value
+func (app *App) signCookieValue(name, value string) (string, error) {
+	keys := app.config.CookieSigningKeys
+	if len(keys) == 0 {
+		return "", ErrCookieNoSigningKeys
+	}
+	sig := cookieSignature([]byte(keys[len(keys)-1]), name, value)
+	return value + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifyCookieValue checks the signature with all keys, the newest first, and returns the value without it
+func (app *App) verifyCookieValue(name, signed string) (string, error) {
+	keys := app.config.CookieSigningKeys
+	if len(keys) == 0 {
+		return "", ErrCookieNoSigningKeys
+	}
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", ErrCookieSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signed[i+1:])
+	if err != nil || len(sig) != sha256.Size {
+		return "", ErrCookieSignature
+	}
+	value := signed[:i]
+	for k := len(keys) - 1; k >= 0; k-- {
+		if hmac.Equal(sig, cookieSignature([]byte(keys[k]), name, value)) {
+			return value, nil
+		}
+	}
+	return "", ErrCookieSignature
 }
@@ -0,0 +1,87 @@
+package lightning
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// go test -run Test_Response_SetSignedCookie
+func Test_Response_SetSignedCookie(t *testing.T) {
+	app := New(Config{CookieSigningKeys: []string{"old-secret", "new-secret"}})
+	app.Get("/set", func(req *Request, res *Response) error {
+		return res.SetSignedCookie(&Cookie{Name: "theme", Value: "dark", HTTPOnly: true})
+	})
+	app.Get("/get", func(req *Request, res *Response) error {
+		value, err := req.SignedCookie("theme")
+		if err != nil {
+			return res.Status(StatusBadRequest).String(err.Error())
+		}
+		return res.String(value)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/set", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	setCookie := resp.Header.Get(HeaderSetCookie)
+	utils.AssertEqual(t, true, strings.HasPrefix(setCookie, "theme=dark."))
+	utils.AssertEqual(t, true, strings.Contains(setCookie, "; HttpOnly"))
+	signed := strings.SplitN(strings.TrimPrefix(setCookie, "theme="), ";", 2)[0]
+
+	sign := func(key, name, value string) string {
+		app := New(Config{CookieSigningKeys: []string{key}})
+		signed, err := app.signCookieValue(name, value)
+		utils.AssertEqual(t, nil, err)
+		return signed
+	}
+	// the newest key signs
+	utils.AssertEqual(t, sign("new-secret", "theme", "dark"), signed)
+
+	testCases := []struct {
+		cookie string
+		status int
+		body   string
+	}{
+		{"theme=" + signed, StatusOK, "dark"},
+		{"theme=" + sign("old-secret", "theme", "light"), StatusOK, "light"},
+		{"", StatusBadRequest, ErrCookieNotFound.Error()},
+		{"theme=dark", StatusBadRequest, ErrCookieSignature.Error()},
+		{"theme=" + strings.Replace(signed, "dark", "light", 1), StatusBadRequest, ErrCookieSignature.Error()},
+		{"theme=" + sign("unknown", "theme", "dark"), StatusBadRequest, ErrCookieSignature.Error()},
+		{"theme=" + sign("new-secret", "lang", "dark"), StatusBadRequest, ErrCookieSignature.Error()},
+		{"theme=dark.!!!", StatusBadRequest, ErrCookieSignature.Error()},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(MethodGet, "/get", nil)
+		if tc.cookie != "" {
+			req.Header.Set(HeaderCookie, tc.cookie)
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, tc.cookie)
+		utils.AssertEqual(t, tc.status, resp.StatusCode, tc.cookie)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, tc.cookie)
+		utils.AssertEqual(t, tc.body, string(body), tc.cookie)
+	}
+}
+
+// go test -run Test_Response_SetSignedCookie_NoKeys
+func Test_Response_SetSignedCookie_NoKeys(t *testing.T) {
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		utils.AssertEqual(t, ErrCookieNoSigningKeys, res.SetSignedCookie(&Cookie{Name: "theme", Value: "dark"}))
+		_, err := req.SignedCookie("theme")
+		utils.AssertEqual(t, ErrCookieNoSigningKeys, err)
+		return nil
+	})
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Set(HeaderCookie, "theme=dark.sig")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderSetCookie))
+}
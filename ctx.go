@@ -44,7 +44,8 @@ const userContextKey = "__local_user_context__"
 // Ctx represents the Context which hold the HTTP request and response.
 // It has methods for the request query string, parameters, body, HTTP headers and so on.
 type Ctx struct {
-	app                 *App                 // Reference to *App
+	app                 *App                 // Reference to *App, the mounted app for its routes
+	router              *App                 // Reference to the *App which routes the request
 	route               *Route               // Reference to *Route
 	indexRoute          int                  // Index of the current route
 	indexHandler        int                  // Index of the current handler
@@ -54,14 +55,24 @@ type Ctx struct {
 	pathBuffer          []byte               // HTTP path buffer
 	detectionPath       string               // Route detection path                                  -> string copy from detectionPathBuffer
 	detectionPathBuffer []byte               // HTTP detectionPath buffer
-	treePath            string               // Path for the search in the tree
+	pathNormalized      bool                 // Detection path differs from the path because of the case or a trailing slash
+	treeStack           []*routeTree         // Radix trees of the app when the request was started
+	treeRoutes          []*Route             // Routes of the tree which can match the detection path
+	treeMethod          int                  // HTTP method INT of the tree routes, -1 if they have to be searched again
+	detectionHost       string               // Lowercase hostname without port for the host detection of the routes
+	version             string               // Requested API version for the versioned routes
+	versionDetected     bool                 // Version was extracted from the request
+	versionMismatch     bool                 // A route matched the path, but not the requested version
 	pathOriginal        string               // Original HTTP path
 	values              [maxParams]string    // Route parameter values
 	fasthttp            *fasthttp.RequestCtx // Reference to *fasthttp.RequestCtx
 	matched             bool                 // Non use route matched
-
-	req *Request
-	res *Response
+	req                 *Request             // Request passed to the handlers, nil until the first handler is called
+	res                 *Response            // Response passed to the handlers, nil until the first handler is called
+	request             Request              // Pooled with the ctx, c.req points to it
+	response            Response             // Pooled with the ctx, c.res points to it
+	reqHeader           HeaderMap            // View over the request headers
+	resHeader           HeaderMap            // View over the response headers
 }
 
 // Range data for c.Range
@@ -99,16 +110,22 @@ func (app *App) AcquireCtx(fctx *fasthttp.RequestCtx) *Ctx {
 	c := app.pool.Get().(*Ctx)
 	// Set app reference
 	c.app = app
+	c.router = app
+	// Keep the current routes for the whole request
+	c.treeStack = app.treeStack.Load().([]*routeTree)
 	// Reset route and handler index
 	c.indexRoute = -1
 	c.indexHandler = 0
 	// Reset matched flag
 	c.matched = false
+	// Reset host and version detection
+	c.detectionHost = ""
+	c.version, c.versionDetected, c.versionMismatch = "", false, false
 	// Set paths
 	c.pathOriginal = app.getString(fctx.URI().PathOriginal())
 	// Set method
 	c.method = app.getString(fctx.Request.Header.Method())
-	c.methodINT = methodInt(c.method)
+	c.methodINT = app.methodInt(c.method)
 	// Attach *fasthttp.RequestCtx to ctx
 	c.fasthttp = fctx
 	// Prettify path
@@ -127,6 +144,9 @@ func (app *App) ReleaseCtx(c *Ctx) {
 	// Reset values
 	c.route = nil
 	c.fasthttp = nil
+	c.response.body = nil
+	c.response.rType = ""
+	c.response.StatusCode = 0
 	app.pool.Put(c)
 }
 
@@ -135,7 +155,8 @@ func (app *App) ReleaseCtxFromReqRes(req *Request, res *Response) {
 	app.ReleaseCtx(req.ctx)
 }
 
-// App returns the *App reference to the instance of the Fiber application
+// App returns the *App reference to the instance of the Fiber application,
+// for the routes of a mounted app it returns the mounted app
 func (c *Ctx) App() *App {
 	return c.app
 }
@@ -240,16 +261,14 @@ func decoderBuilder(parserConfig ParserConfig) interface{} {
 
 // BodyParser binds the request body to a struct.
 // It supports decoding the following content types based on the Content-Type header:
-// application/json, application/xml, application/x-www-form-urlencoded, multipart/form-data
+// application/x-www-form-urlencoded, multipart/form-data and the media types of the decoder registry,
+// which contains application/json, application/xml, application/msgpack and text/plain by default.
 // If none of the content types above are matched, it will return a ErrUnprocessableEntity error
 func (c *Ctx) BodyParser(out interface{}) error {
 	// Get content-type
 	cType := utils.ParseVendorSpecificContentType(utils.ToLower(utils.UnsafeString(c.fasthttp.Request.Header.ContentType())))
 
 	// Parse body accordingly
-	if strings.HasPrefix(cType, MIMEApplicationJSON) {
-		return c.app.config.JSONDecoder(c.Body(), out)
-	}
 	if strings.HasPrefix(cType, MIMEApplicationForm) {
 		data := make(map[string][]string)
 		c.fasthttp.PostArgs().VisitAll(func(key, val []byte) {
@@ -276,8 +295,8 @@ func (c *Ctx) BodyParser(out interface{}) error {
 		}
 		return c.parseToStruct(bodyTag, out, data.Value)
 	}
-	if strings.HasPrefix(cType, MIMETextXML) || strings.HasPrefix(cType, MIMEApplicationXML) {
-		return xml.Unmarshal(c.Body(), out)
+	if decoder, ok := c.app.decoders.Load().(map[string]Decoder)[parseMediaType(cType)]; ok {
+		return decoder(c.Body(), out)
 	}
 	// No suitable content type found
 	return ErrUnprocessableEntity
@@ -329,7 +348,7 @@ func (c *Ctx) Cookie(cookie *Cookie) {
 	fcookie.SetDomain(cookie.Domain)
 	fcookie.SetMaxAge(cookie.MaxAge)
 	fcookie.SetExpire(cookie.Expires)
-	fcookie.SetSecure(cookie.Secure)
+	fcookie.SetSecure(cookie.Secure || cookie.Partitioned)
 	fcookie.SetHTTPOnly(cookie.HTTPOnly)
 
 	switch utils.ToLower(cookie.SameSite) {
@@ -343,8 +362,28 @@ func (c *Ctx) Cookie(cookie *Cookie) {
 		fcookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
 	}
 
-	c.fasthttp.Response.Header.SetCookie(fcookie)
+	if cookie.Priority == "" && !cookie.Partitioned {
+		c.fasthttp.Response.Header.SetCookie(fcookie)
+		fasthttp.ReleaseCookie(fcookie)
+		return
+	}
+
+	// fasthttp doesn't know the Priority and Partitioned attributes, they are appended to the header value
+	raw := fcookie.AppendBytes(nil)
 	fasthttp.ReleaseCookie(fcookie)
+	switch utils.ToLower(cookie.Priority) {
+	case CookiePriorityLow:
+		raw = append(raw, "; Priority=Low"...)
+	case CookiePriorityMedium:
+		raw = append(raw, "; Priority=Medium"...)
+	case CookiePriorityHigh:
+		raw = append(raw, "; Priority=High"...)
+	}
+	if cookie.Partitioned {
+		raw = append(raw, "; Partitioned"...)
+	}
+	c.fasthttp.Response.Header.DelCookie(cookie.Name)
+	c.fasthttp.Response.Header.SetBytesV(HeaderSetCookie, raw)
 }
 
 // Cookies is used for getting a cookie value by key.
@@ -669,7 +708,7 @@ func (c *Ctx) Location(path string) {
 func (c *Ctx) Method(override ...string) string {
 	if len(override) > 0 {
 		method := utils.ToUpper(override[0])
-		mINT := methodInt(method)
+		mINT := c.router.methodInt(method)
 		if mINT == -1 {
 			return c.method
 		}
@@ -695,7 +734,7 @@ func (c *Ctx) Next() (err error) {
 		err = c.route.Handlers[c.indexHandler](buildRouteCallback(c))
 	} else {
 		// Continue handler stack
-		_, err = c.app.next(buildRouteCallback(c))
+		_, err = c.router.next(buildRouteCallback(c))
 	}
 	return err
 }
@@ -1253,7 +1292,7 @@ func (c *Ctx) XHR() bool {
 func (c *Ctx) configDependentPaths() {
 	c.pathBuffer = append(c.pathBuffer[0:0], c.pathOriginal...)
 	// If UnescapePath enabled, we decode the path and save it for the framework user
-	if c.app.config.UnescapePath {
+	if c.router.config.UnescapePath {
 		c.pathBuffer = fasthttp.AppendUnquotedArg(c.pathBuffer[:0], c.pathBuffer)
 	}
 	c.path = c.app.getString(c.pathBuffer)
@@ -1262,21 +1301,101 @@ func (c *Ctx) configDependentPaths() {
 	// use the path that was changed by the previous configuration flags
 	c.detectionPathBuffer = append(c.detectionPathBuffer[0:0], c.pathBuffer...)
 	// If CaseSensitive is disabled, we lowercase the original path
-	if !c.app.config.CaseSensitive {
+	if !c.router.config.CaseSensitive {
 		c.detectionPathBuffer = utils.ToLowerBytes(c.detectionPathBuffer)
 	}
 	// If StrictRouting is disabled, we strip all trailing slashes
-	if !c.app.config.StrictRouting && len(c.detectionPathBuffer) > 1 && c.detectionPathBuffer[len(c.detectionPathBuffer)-1] == '/' {
+	if !c.router.config.StrictRouting && len(c.detectionPathBuffer) > 1 && c.detectionPathBuffer[len(c.detectionPathBuffer)-1] == '/' {
 		c.detectionPathBuffer = utils.TrimRightBytes(c.detectionPathBuffer, '/')
 	}
 	c.detectionPath = c.app.getString(c.detectionPathBuffer)
+	// Remember if the case or a trailing slash was changed for the redirect to the fixed path
+	c.pathNormalized = c.router.config.RedirectFixedPath && c.detectionPath != c.path
+
+	// The routes of the tree have to be searched again for the new detection path
+	c.treeMethod = -1
+}
+
+// lookupTree returns the routes of the method which can match the detection path,
+// the result is cached until the detection path or the method changes
+func (c *Ctx) lookupTree(methodINT int) []*Route {
+	if c.treeMethod != methodINT {
+		c.treeRoutes = c.treeStack[methodINT].lookup(c.detectionPath, c.treeRoutes[:0])
+		c.treeMethod = methodINT
+	}
+	return c.treeRoutes
+}
+
+// fixedPath returns the registered form of the request path if the route only matches
+// after the normalization of the case or the trailing slash, otherwise an empty string.
+// The unescaped param values are escaped again, so they can't change the meaning of the location.
+func (c *Ctx) fixedPath(route *Route) string {
+	// Neither the request nor the route have been normalized
+	if !c.pathNormalized && route.Path == route.path {
+		return ""
+	}
+	var fixed, location string
+	if len(route.Params) == 0 && len(route.rawParser.variants) == 0 {
+		fixed = RemoveEscapeChar(route.Path)
+		location = fixed
+	} else {
+		segs, positions := route.rawParser.segs, []int(nil)
+		// Find the matched variant of the optional groups
+		if len(route.rawParser.variants) > 0 {
+			var values [maxParams]string
+			for i, variant := range route.routeParser.variants {
+				if variant.parser.getMatch(c.detectionPath, c.path, &values, route.use) {
+					segs, positions = route.rawParser.variants[i].parser.segs, route.rawParser.variants[i].paramPositions
+					break
+				}
+			}
+		}
+		var sb, lb strings.Builder
+		paramsIterator := 0
+		for _, segment := range segs {
+			if !segment.IsParam {
+				sb.WriteString(segment.Const)
+				lb.WriteString(segment.Const)
+				continue
+			}
+			pos := paramsIterator
+			if positions != nil {
+				pos = positions[paramsIterator]
+			}
+			sb.WriteString(c.values[pos])
+			if c.router.config.UnescapePath {
+				lb.WriteString(escapePathParam(c.values[pos], segment.IsGreedy))
+			} else {
+				lb.WriteString(c.values[pos])
+			}
+			paramsIterator++
+		}
+		fixed, location = sb.String(), lb.String()
+		// A missing optional param at the end leaves a trailing slash, e.g. "/users/:id?"
+		if len(fixed) > 1 && fixed[len(fixed)-1] == '/' && route.Path[len(route.Path)-1] != '/' {
+			fixed = utils.TrimRight(fixed, '/')
+			location = utils.TrimRight(location, '/')
+		}
+	}
+	// Compared with the path, which is unescaped like the values
+	if fixed == c.path {
+		return ""
+	}
+	return location
+}
 
-	// Define the path for dividing routes into areas for fast tree detection, so that fewer routes need to be traversed,
-	// since the first three characters area select a list of routes
-	c.treePath = c.treePath[0:0]
-	if len(c.detectionPath) >= 3 {
-		c.treePath = c.detectionPath[:3]
+// redirectFixedPath redirects to the fixed path and keeps the query string,
+// 301 is used for GET and HEAD requests and 308 for all other request methods
+func (c *Ctx) redirectFixedPath(location string) error {
+	status := StatusPermanentRedirect
+	if c.method == MethodGet || c.method == MethodHead {
+		status = StatusMovedPermanently
 	}
+	if query := c.fasthttp.URI().QueryString(); len(query) > 0 {
+		location += "?" + c.app.getString(query)
+	}
+	c.fasthttp.Response.ResetBody()
+	return c.Redirect(location, status)
 }
 
 func (c *Ctx) IsProxyTrusted() bool {
@@ -1317,21 +1436,15 @@ func (c *Ctx) IsFromLocal() bool {
 	return c.isLocalHost(ips[0])
 }
 
+// buildRouteCallback returns the request and response of the ctx for the handlers,
+// they are pooled with the ctx and only bound to it the first time the ctx is used.
 func buildRouteCallback(c *Ctx) (*Request, *Response) {
-	c.req = &Request{
-		ctx: c,
-		Header: &HeaderMap{
-			ctx: c,
-			Map: c.GetReqHeaders(),
-		},
-	}
-	c.res = &Response{
-		ctx: c,
-		Header: &HeaderMap{
-			ctx: c,
-			Map: c.GetRespHeaders(),
-		},
+	if c.req == nil {
+		c.reqHeader = HeaderMap{ctx: c}
+		c.resHeader = HeaderMap{ctx: c, response: true}
+		c.request = Request{ctx: c, Header: &c.reqHeader}
+		c.response = Response{ctx: c, Header: &c.resHeader}
+		c.req, c.res = &c.request, &c.response
 	}
-
 	return c.req, c.res
 }
@@ -660,6 +660,24 @@ func Test_Ctx_Cookie(t *testing.T) {
 	cookie.SameSite = CookieSameSiteNoneMode
 	c.Cookie(cookie)
 	utils.AssertEqual(t, expect, string(c.Response().Header.Peek(HeaderSetCookie)))
+
+	// a partitioned cookie is always secure and replaces the previous cookie
+	expect = "username=john; expires=" + httpdate + "; path=/; secure; SameSite=None; Priority=High; Partitioned"
+	cookie.Secure = false
+	cookie.Partitioned = true
+	cookie.Priority = "High"
+	c.Cookie(cookie)
+	var cookies []string
+	c.Response().Header.VisitAll(func(key, value []byte) {
+		if string(key) == HeaderSetCookie {
+			cookies = append(cookies, string(value))
+		}
+	})
+	utils.AssertEqual(t, []string{expect}, cookies)
+
+	expect = "username=john; expires=" + httpdate + "; path=/; SameSite=Lax; Priority=Low"
+	c.Cookie(&Cookie{Name: "username", Value: "john", Expires: expire, Priority: CookiePriorityLow})
+	utils.AssertEqual(t, expect, string(c.Response().Header.Peek(HeaderSetCookie)))
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_Cookie -benchmem -count=4
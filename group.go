@@ -13,9 +13,11 @@ import (
 
 // Group struct
 type Group struct {
-	app    *App
-	prefix string
-	name   string
+	app     *App
+	prefix  string
+	name    string
+	host    *routeHost
+	version string
 }
 
 // Mount attaches another app instance as a sub-router along a routing path.
@@ -27,8 +29,11 @@ func (grp *Group) Mount(prefix string, lighting *App) Router {
 
 	for m := range stack {
 		for r := range stack[m] {
-			route := grp.app.copyRoute(stack[m][r])
-			grp.app.addRoute(route.Method, grp.app.addPrefixToRoute(groupPath, route))
+			if !grp.app.mountable(stack[m][r]) {
+				continue
+			}
+			route := grp.app.copyRoute(stack[m][r]).withGroup(grp)
+			grp.app.mustAddRoute(grp.app.addPrefixToRoute(groupPath, route), route.Method)
 		}
 	}
 
@@ -81,15 +86,15 @@ func (grp *Group) Use(args ...interface{}) Router {
 			panic(fmt.Sprintf("use: invalid handler %v\n", reflect.TypeOf(arg)))
 		}
 	}
-	grp.app.register(methodUse, getGroupPath(grp.prefix, prefix), handlers...)
+	grp.app.register(methodUse, getGroupPath(grp.prefix, prefix), grp, handlers...)
 	return grp
 }
 
 // Get registers a route for GET methods that requests a representation
 // of the specified resource. Requests using GET should only retrieve data.
 func (grp *Group) Get(path string, handlers ...Handler) Router {
-	path = getGroupPath(grp.prefix, path)
-	return grp.app.Add(MethodHead, path, handlers...).Add(MethodGet, path, handlers...)
+	_ = grp.Add(MethodHead, path, handlers...)
+	return grp.Add(MethodGet, path, handlers...)
 }
 
 // Head registers a route for HEAD methods that asks for a response identical
@@ -141,17 +146,17 @@ func (grp *Group) Patch(path string, handlers ...Handler) Router {
 
 // Add allows you to specify a HTTP method to register a route
 func (grp *Group) Add(method, path string, handlers ...Handler) Router {
-	return grp.app.register(method, getGroupPath(grp.prefix, path), handlers...)
+	return grp.app.register(method, getGroupPath(grp.prefix, path), grp, handlers...)
 }
 
 // Static will create a file server serving static files
 func (grp *Group) Static(prefix, root string, config ...Static) Router {
-	return grp.app.registerStatic(getGroupPath(grp.prefix, prefix), root, config...)
+	return grp.app.registerStatic(getGroupPath(grp.prefix, prefix), root, grp, config...)
 }
 
 // All will register the handler on all HTTP methods
 func (grp *Group) All(path string, handlers ...Handler) Router {
-	for _, method := range intMethod {
+	for _, method := range grp.app.config.RequestMethods {
 		_ = grp.Add(method, path, handlers...)
 	}
 	return grp
@@ -163,9 +168,9 @@ func (grp *Group) All(path string, handlers ...Handler) Router {
 func (grp *Group) Group(prefix string, handlers ...Handler) Router {
 	prefix = getGroupPath(grp.prefix, prefix)
 	if len(handlers) > 0 {
-		_ = grp.app.register(methodUse, prefix, handlers...)
+		_ = grp.app.register(methodUse, prefix, grp, handlers...)
 	}
-	return grp.app.Group(prefix)
+	return &Group{prefix: prefix, app: grp.app, host: grp.host, version: grp.version}
 }
 
 // Route is used to define routes with a common prefix inside the common function.
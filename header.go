@@ -1,41 +1,112 @@
 package lightning
 
-import "strings"
+import (
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp"
+)
 
+// HeaderMap is a view over the request or the response headers of fasthttp, the keys are case-insensitive.
+// Nothing is copied until All is called, so the returned values are only valid within the handler.
+// Make copies or use the Immutable setting instead.
 type HeaderMap struct {
-	Map map[string]string
-	ctx *Ctx
+	ctx      *Ctx
+	response bool
 }
 
-func (hm *HeaderMap) init(headers map[string]string) *HeaderMap {
-	hm.Map = make(map[string]string)
-	hm.Map = headers
-	return hm
+// fasthttpHeader is implemented by fasthttp.RequestHeader and fasthttp.ResponseHeader
+type fasthttpHeader interface {
+	Peek(key string) []byte
+	Set(key, value string)
+	Add(key, value string)
+	Del(key string)
+	VisitAll(f func(key, value []byte))
+	ContentType() []byte
+	SetContentType(contentType string)
 }
 
+// header returns the fasthttp headers of the view
+func (hm *HeaderMap) header() fasthttpHeader {
+	if hm.response {
+		return &hm.ctx.fasthttp.Response.Header
+	}
+	return &hm.ctx.fasthttp.Request.Header
+}
+
+// Get returns the first value of the header or the default value if the header is empty
+func (hm *HeaderMap) Get(key string, defaultValue ...string) string {
+	return defaultString(hm.ctx.app.getString(hm.header().Peek(key)), defaultValue)
+}
+
+// Values returns all values of a repeated header like Set-Cookie, Via or Accept in the order they were received
+func (hm *HeaderMap) Values(key string) (values []string) {
+	hm.header().VisitAll(func(k, v []byte) {
+		if utils.EqualFold(utils.UnsafeString(k), key) {
+			values = append(values, hm.ctx.app.getString(v))
+		}
+	})
+	return values
+}
+
+// Has checks if the header is present, also with an empty value
+func (hm *HeaderMap) Has(key string) (found bool) {
+	if len(hm.header().Peek(key)) > 0 {
+		return true
+	}
+	hm.header().VisitAll(func(k, _ []byte) {
+		found = found || utils.EqualFold(utils.UnsafeString(k), key)
+	})
+	return found
+}
+
+// Set replaces all values of the header
 func (hm *HeaderMap) Set(key, value string) {
-	hm.Map[strings.ToLower(key)] = value
-	hm.ctx.Set(key, value)
+	hm.header().Set(key, value)
 }
 
-func (hm *HeaderMap) ContentType() string {
-	return string(hm.ctx.Request().Header.ContentType())
+// Add appends a value to the header, the header is repeated.
+// Content-Type, Content-Length, Connection, Server, Set-Cookie with the same cookie name,
+// Transfer-Encoding and Date can only be set once and replace the previous value.
+func (hm *HeaderMap) Add(key, value string) {
+	hm.header().Add(key, value)
 }
 
-func (hm *HeaderMap) SetContentType(contentType string) {
-	hm.ctx.Response().Header.SetContentType(contentType)
+// Del removes all values of the header
+func (hm *HeaderMap) Del(key string) {
+	hm.header().Del(key)
 }
 
-func (hm *HeaderMap) All() map[string]string {
-	return hm.Map
+// VisitAll calls fn for every header value, repeated headers are visited once per value
+func (hm *HeaderMap) VisitAll(fn func(key, value string)) {
+	hm.header().VisitAll(func(k, v []byte) {
+		fn(hm.ctx.app.getString(k), hm.ctx.app.getString(v))
+	})
 }
 
-// Get retrieves the value of a given header
-func (hm *HeaderMap) Get(key string, defaultString ...string) string {
-	if val, ok := hm.Map[strings.ToLower(key)]; ok {
-		if ok {
-			return val
+// All copies the headers into a map, the values of repeated headers are joined with ", "
+func (hm *HeaderMap) All() map[string]string {
+	headers := make(map[string]string)
+	hm.header().VisitAll(func(k, v []byte) {
+		key := string(k)
+		if prev, ok := headers[key]; ok {
+			headers[key] = prev + ", " + string(v)
+		} else {
+			headers[key] = string(v)
 		}
-	}
-	return hm.ctx.Get(key, defaultString...)
+	})
+	return headers
 }
+
+// ContentType returns the Content-Type header
+func (hm *HeaderMap) ContentType() string {
+	return hm.ctx.app.getString(hm.header().ContentType())
+}
+
+// SetContentType sets the Content-Type header
+func (hm *HeaderMap) SetContentType(contentType string) {
+	hm.header().SetContentType(contentType)
+}
+
+var (
+	_ fasthttpHeader = (*fasthttp.RequestHeader)(nil)
+	_ fasthttpHeader = (*fasthttp.ResponseHeader)(nil)
+)
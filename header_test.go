@@ -0,0 +1,101 @@
+package lightning
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// go test -run Test_HeaderMap_Request
+func Test_HeaderMap_Request(t *testing.T) {
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		utils.AssertEqual(t, "1.0 fred", req.Header.Get("via"))
+		utils.AssertEqual(t, "default", req.Header.Get("X-Missing", "default"))
+		utils.AssertEqual(t, []string{"1.0 fred", "1.1 p.example.net"}, req.Header.Values(HeaderVia))
+		utils.AssertEqual(t, []string(nil), req.Header.Values("X-Missing"))
+		utils.AssertEqual(t, true, req.Header.Has("x-empty"))
+		utils.AssertEqual(t, false, req.Header.Has("X-Missing"))
+		utils.AssertEqual(t, "1.0 fred, 1.1 p.example.net", req.Header.All()[HeaderVia])
+
+		req.Header.Add(HeaderVia, "1.1 lightning")
+		utils.AssertEqual(t, 3, len(req.Header.Values(HeaderVia)))
+		req.Header.Del(HeaderVia)
+		utils.AssertEqual(t, false, req.Header.Has(HeaderVia))
+		req.Header.Set("X-Custom", "1")
+		utils.AssertEqual(t, "1", req.Header.Get("x-custom"))
+
+		var keys []string
+		req.Header.VisitAll(func(key, value string) {
+			keys = append(keys, key)
+		})
+		sort.Strings(keys)
+		return res.String(strings.Join(keys, ","))
+	})
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Add(HeaderVia, "1.0 fred")
+	req.Header.Add(HeaderVia, "1.1 p.example.net")
+	req.Header.Set("X-Empty", "")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "Host,X-Custom,X-Empty", string(body))
+
+	// the request headers aren't sent back
+	utils.AssertEqual(t, "", resp.Header.Get("X-Custom"))
+}
+
+// go test -run Test_HeaderMap_Response
+func Test_HeaderMap_Response(t *testing.T) {
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		res.Header.Add(HeaderSetCookie, "a=1")
+		res.Header.Add(HeaderSetCookie, "b=2")
+		res.Header.Add(HeaderVary, HeaderAccept)
+		res.Header.Add(HeaderVary, HeaderOrigin)
+		res.Header.Set("X-Removed", "1")
+		res.Header.Del("x-removed")
+		res.Header.SetContentType(MIMEApplicationJSON)
+
+		utils.AssertEqual(t, []string{"a=1", "b=2"}, res.Header.Values(HeaderSetCookie))
+		utils.AssertEqual(t, MIMEApplicationJSON, res.Header.ContentType())
+		utils.AssertEqual(t, MIMEApplicationJSON, res.Header.Get(HeaderContentType))
+		utils.AssertEqual(t, false, res.Header.Has("X-Removed"))
+		utils.AssertEqual(t, "", res.Header.Get(HeaderVia))
+		return nil
+	})
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Set(HeaderVia, "1.0 fred")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, []string{"a=1", "b=2"}, resp.Header.Values(HeaderSetCookie))
+	utils.AssertEqual(t, []string{HeaderAccept, HeaderOrigin}, resp.Header.Values(HeaderVary))
+	utils.AssertEqual(t, "", resp.Header.Get("X-Removed"))
+}
+
+// go test -v -run=^$ -bench=Benchmark_HeaderMap_Get -benchmem -count=4
+func Benchmark_HeaderMap_Get(b *testing.B) {
+	app := New()
+	req, res := app.AcquireReqRes(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtxFromReqRes(req, res)
+	req.ctx.fasthttp.Request.Header.Set(HeaderAccept, MIMEApplicationJSON)
+	req.ctx.fasthttp.Request.Header.Set(HeaderUserAgent, "lightning")
+
+	var value string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		value = req.Header.Get(HeaderAccept)
+	}
+	utils.AssertEqual(b, MIMEApplicationJSON, value)
+}
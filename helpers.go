@@ -110,43 +110,92 @@ func (app *App) quoteString(raw string) string {
 
 // Scan stack if other methods match the request
 func methodExist(ctx *Ctx) (exist bool) {
-	for i := 0; i < len(intMethod); i++ {
+	methods := ctx.router.config.RequestMethods
+	// Bitmask of the matched methods, there are at most maxMethods
+	var matched uint64
+	for i := 0; i < len(methods); i++ {
 		// Skip original method
 		if ctx.methodINT == i {
 			continue
 		}
-		// Reset stack index
-		ctx.indexRoute = -1
-		tree, ok := ctx.app.treeStack[i][ctx.treePath]
-		if !ok {
-			tree = ctx.app.treeStack[i][""]
+		if methodMatch(ctx, i) {
+			matched |= 1 << uint(i)
 		}
-		// Get stack length
-		lenr := len(tree) - 1
-		// Loop over the route stack starting from previous index
-		for ctx.indexRoute < lenr {
-			// Increment route index
-			ctx.indexRoute++
-			// Get *Route
-			route := tree[ctx.indexRoute]
-			// Skip use routes
-			if route.use {
-				continue
-			}
-			// Check if it matches the request path
-			match := route.match(ctx.detectionPath, ctx.path, &ctx.values)
-			// No match, next route
-			if match {
-				// We matched
-				exist = true
-				// Add method to Allow header
-				ctx.Append(HeaderAllow, intMethod[i])
-				// Break stack loop
+	}
+	if matched == 0 {
+		return false
+	}
+	get := ctx.router.methodInt(MethodGet)
+	for i := 0; i < len(methods); i++ {
+		if ctx.methodINT == i {
+			continue
+		}
+		found := matched&(1<<uint(i)) != 0
+		// Add the automatically answered methods, HEAD is served by GET routes
+		if !found && methods[i] == MethodHead && ctx.router.config.AutoHead {
+			found = get != -1 && ctx.methodINT != get && matched&(1<<uint(get)) != 0
+		} else if !found && methods[i] == MethodOptions {
+			found = ctx.router.config.AutoOptions
+		}
+		if found {
+			// Add method to Allow header
+			ctx.Append(HeaderAllow, methods[i])
+		}
+	}
+	return true
+}
+
+// methodMatch checks if a route of the method, which is not a middleware, matches the request
+func methodMatch(ctx *Ctx, methodINT int) bool {
+	// Reset stack index
+	ctx.indexRoute = -1
+	tree := ctx.lookupTree(methodINT)
+	// Get stack length
+	lenr := len(tree) - 1
+	// Loop over the route stack starting from previous index
+	for ctx.indexRoute < lenr {
+		// Increment route index
+		ctx.indexRoute++
+		// Get *Route
+		route := tree[ctx.indexRoute]
+		// Skip use routes
+		if route.use {
+			continue
+		}
+		// Check if it matches the request path
+		if ctx.matchRoute(route) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxMethods is the maximum amount of request methods, methodExist keeps the matched methods in a bitmask
+const maxMethods = 64
+
+// normalizeMethods returns a copy of the methods in uppercase without duplicates
+func normalizeMethods(methods []string) []string {
+	normalized := make([]string, 0, len(methods))
+	for _, method := range methods {
+		method = utils.ToUpper(method)
+		if method == "" {
+			panic("route: request method must not be empty\n")
+		}
+		found := false
+		for _, m := range normalized {
+			if m == method {
+				found = true
 				break
 			}
 		}
+		if !found {
+			normalized = append(normalized, method)
+		}
 	}
-	return
+	if len(normalized) > maxMethods {
+		panic(fmt.Sprintf("route: at most %d request methods are supported\n", maxMethods))
+	}
+	return normalized
 }
 
 // uniqueRouteStack drop all not unique routes from the slice
@@ -373,6 +422,22 @@ var getBytesImmutable = func(s string) (b []byte) {
 	return []byte(s)
 }
 
+// methodInt returns the unique INT of the method in the RequestMethods of the app, -1 if the method is unknown
+func (app *App) methodInt(s string) int {
+	// The default methods are found without a loop
+	if app.defaultMethods {
+		if m := methodInt(s); m != -1 {
+			return m
+		}
+	}
+	for i := app.customMethods; i < len(app.config.RequestMethods); i++ {
+		if app.config.RequestMethods[i] == s {
+			return i
+		}
+	}
+	return -1
+}
+
 // HTTP methods and their unique INTs
 func methodInt(s string) int {
 	switch s {
@@ -399,8 +464,9 @@ func methodInt(s string) int {
 	}
 }
 
-// HTTP methods slice
-var intMethod = []string{
+// defaultRequestMethods are the HTTP methods of the router if Config.RequestMethods is not set,
+// the position in the slice is the unique INT of the method, see methodInt
+var defaultRequestMethods = []string{
 	MethodGet,
 	MethodHead,
 	MethodPost,
@@ -412,6 +478,11 @@ var intMethod = []string{
 	MethodPatch,
 }
 
+// DefaultMethods returns a copy of the HTTP methods of the router if Config.RequestMethods is not set
+func DefaultMethods() []string {
+	return append([]string(nil), defaultRequestMethods...)
+}
+
 // HTTP methods were copied from net/http.
 const (
 	MethodGet     = "GET"     // RFC 7231, 4.3.1
@@ -428,15 +499,17 @@ const (
 
 // MIME types that are commonly used
 const (
-	MIMETextXML               = "text/xml"
-	MIMETextHTML              = "text/html"
-	MIMETextPlain             = "text/plain"
-	MIMEApplicationXML        = "application/xml"
-	MIMEApplicationJSON       = "application/json"
-	MIMEApplicationJavaScript = "application/javascript"
-	MIMEApplicationForm       = "application/x-www-form-urlencoded"
-	MIMEOctetStream           = "application/octet-stream"
-	MIMEMultipartForm         = "multipart/form-data"
+	MIMETextXML                = "text/xml"
+	MIMETextHTML               = "text/html"
+	MIMETextPlain              = "text/plain"
+	MIMEApplicationXML         = "application/xml"
+	MIMEApplicationJSON        = "application/json"
+	MIMEApplicationProblemJSON = "application/problem+json"
+	MIMEApplicationJavaScript  = "application/javascript"
+	MIMEApplicationForm        = "application/x-www-form-urlencoded"
+	MIMEApplicationMsgPack     = "application/msgpack"
+	MIMEOctetStream            = "application/octet-stream"
+	MIMEMultipartForm          = "multipart/form-data"
 
 	MIMETextXMLCharsetUTF8               = "text/xml; charset=utf-8"
 	MIMETextHTMLCharsetUTF8              = "text/html; charset=utf-8"
@@ -660,7 +733,7 @@ const (
 	HeaderSecWebSocketExtensions  = "Sec-WebSocket-Extensions"
 	HeaderSecWebSocketKey         = "Sec-WebSocket-Key"
 	HeaderSecWebSocketProtocol    = "Sec-WebSocket-Protocol"
-	HeaderSecWebSocketVersion     = "Sec-WebSocket-FiberVersion"
+	HeaderSecWebSocketVersion     = "Sec-WebSocket-Version"
 	HeaderAcceptPatch             = "Accept-Patch"
 	HeaderAcceptPushPolicy        = "Accept-Push-Policy"
 	HeaderAcceptSignature         = "Accept-Signature"
@@ -676,6 +749,7 @@ const (
 	HeaderSignedHeaders           = "Signed-Headers"
 	HeaderSourceMap               = "SourceMap"
 	HeaderUpgrade                 = "Upgrade"
+	HeaderXAPIVersion             = "X-API-Version"
 	HeaderXDNSPrefetchControl     = "X-DNS-Prefetch-Control"
 	HeaderXPingback               = "X-Pingback"
 	HeaderXRequestID              = "X-Request-Id"
@@ -707,3 +781,10 @@ const (
 	CookieSameSiteStrictMode = "strict"
 	CookieSameSiteNoneMode   = "none"
 )
+
+// Cookie Priority, not in RFC, supported by Chromium based browsers
+const (
+	CookiePriorityLow    = "low"
+	CookiePriorityMedium = "medium"
+	CookiePriorityHigh   = "high"
+)
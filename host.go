@@ -0,0 +1,112 @@
+package lightning
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// routeHost holds the parsed host pattern of a route, e.g. "api.example.com" or ":tenant.example.com"
+type routeHost struct {
+	pattern string      // Original host pattern
+	parser  routeParser // Parser of the lowercase host pattern
+	params  []string    // Case sensitive param keys of the host
+}
+
+// parseHost parses the host pattern, hostnames are always compared case-insensitive
+func parseHost(pattern string) *routeHost {
+	if pattern == "" {
+		panic("host: pattern must not be empty\n")
+	}
+	if strings.IndexByte(pattern, '/') != -1 {
+		panic(fmt.Sprintf("host: pattern must not contain a path %s\n", pattern))
+	}
+	return &routeHost{
+		pattern: pattern,
+		parser:  parseRoute(toLowerRoute(pattern)),
+		params:  parseRoute(pattern).params,
+	}
+}
+
+// match checks the hostname against the host pattern and stores the host params at the given offset
+func (h *routeHost) match(hostname string, offset int, params *[maxParams]string) bool {
+	if len(h.params) == 0 {
+		return h.parser.segs[0].Const == hostname
+	}
+	var values [maxParams]string
+	if !h.parser.getMatch(hostname, hostname, &values, false) {
+		return false
+	}
+	copy(params[offset:], values[:len(h.params)])
+	return true
+}
+
+// detectionHostname returns the lowercase hostname of the request without the port for the host matching
+func (c *Ctx) detectionHostname() string {
+	if c.detectionHost == "" {
+		host := c.Hostname()
+		// Strip the port, IPv6 addresses are enclosed in brackets
+		if i := strings.LastIndexByte(host, ':'); i != -1 && i > strings.LastIndexByte(host, ']') {
+			host = host[:i]
+		}
+		c.detectionHost = utils.ToLower(host)
+	}
+	return c.detectionHost
+}
+
+// matchRoute checks if the route matches the host and the path of the request
+func (c *Ctx) matchRoute(route *Route) bool {
+	if route.host != nil && !route.host.match(c.detectionHostname(), len(route.Params)-len(route.host.params), &c.values) {
+		return false
+	}
+	if !route.match(c.detectionPath, c.path, &c.values) {
+		return false
+	}
+	if route.Version != "" && route.Version != c.requestVersion() {
+		// Remember the mismatch for the 406 response, middlewares don't register a path
+		c.versionMismatch = c.versionMismatch || !route.use
+		return false
+	}
+	return true
+}
+
+// withGroup restricts the route to the host and the version of the group
+func (r *Route) withGroup(grp *Group) *Route {
+	if grp == nil {
+		return r
+	}
+	if grp.version != "" && r.Version == "" {
+		r.Version = grp.version
+	}
+	return r.withHost(grp.host)
+}
+
+// withHost adds the host and its params to the route
+func (r *Route) withHost(host *routeHost) *Route {
+	if host == nil || r.host != nil {
+		return r
+	}
+	if len(r.Params)+len(host.params) > maxParams {
+		panic(fmt.Sprintf("host: too many params in %s%s\n", host.pattern, r.Path))
+	}
+	r.host = host
+	r.Host = host.pattern
+	r.Params = append(append(make([]string, 0, len(r.Params)+len(host.params)), r.Params...), host.params...)
+	return r
+}
+
+// Host is used for routes which only match requests to the given hostname, the pattern can contain
+// parameters which are readable through req.Param, the port of the request is ignored.
+//  tenant := app.Host(":tenant.example.com")
+//  tenant.Get("/", handler) // req.Param("tenant")
+func (app *App) Host(pattern string) Router {
+	return &Group{app: app, host: parseHost(pattern)}
+}
+
+// Host is used for routes of the group which only match requests to the given hostname.
+//  api := app.Group("/api").Host("api.example.com")
+//  api.Get("/users", handler)
+func (grp *Group) Host(pattern string) Router {
+	return &Group{app: grp.app, prefix: grp.prefix, name: grp.name, host: parseHost(pattern), version: grp.version}
+}
@@ -0,0 +1,156 @@
+package lightning
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// go test -run Test_Host_Routing
+func Test_Host_Routing(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	app.Host("api.example.com").Get("/", func(req *Request, res *Response) error {
+		return res.String("api")
+	})
+	app.Host("admin.example.com").Get("/", func(req *Request, res *Response) error {
+		return res.String("admin")
+	})
+	app.Host(":tenant.example.com").Get("/users/:id", func(req *Request, res *Response) error {
+		return res.String(req.Param("tenant") + " " + req.Param("id"))
+	})
+	app.Get("/", func(req *Request, res *Response) error {
+		return res.String("default")
+	})
+
+	testCases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{url: "http://api.example.com/", code: StatusOK, body: "api"},
+		{url: "http://admin.example.com/", code: StatusOK, body: "admin"},
+		{url: "http://API.Example.com:8080/", code: StatusOK, body: "api"},
+		{url: "http://www.example.com/", code: StatusOK, body: "default"},
+		{url: "http://acme.example.com/users/42", code: StatusOK, body: "acme 42"},
+		{url: "http://ACME.example.com:3000/users/42", code: StatusOK, body: "acme 42"},
+		{url: "http://example.com/users/42", code: StatusNotFound, body: "Cannot GET /users/42"},
+		{url: "http://acme.example.org/users/42", code: StatusNotFound, body: "Cannot GET /users/42"},
+	}
+	for _, tc := range testCases {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, tc.url, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, tc.code, resp.StatusCode, tc.url)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.body, string(body), tc.url)
+	}
+}
+
+// go test -run Test_Host_Group
+func Test_Host_Group(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	api := app.Group("/api").Host(":tenant.example.com")
+	api.Use(func(req *Request, res *Response) error {
+		res.Header.Set("X-Tenant", req.Param("tenant"))
+		return req.Next()
+	})
+	v1 := api.Group("/v1")
+	v1.Get("/users/:id", func(req *Request, res *Response) error {
+		return res.String(req.Param("id"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "http://acme.example.com/api/v1/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "acme", resp.Header.Get("X-Tenant"))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "http://localhost/api/v1/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "", resp.Header.Get("X-Tenant"))
+
+	// Other methods of the same host are reported as not allowed
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "http://acme.example.com/api/v1/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode, "Status code")
+
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "http://localhost/api/v1/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+}
+
+// go test -run Test_Host_Mount
+func Test_Host_Mount(t *testing.T) {
+	t.Parallel()
+	admin := New()
+	admin.Get("/users/:id", func(req *Request, res *Response) error {
+		return res.String(req.Param("tenant") + " " + req.Param("id"))
+	})
+	admin.Host("static.example.com").Get("/logo", func(req *Request, res *Response) error {
+		return res.String("logo")
+	})
+
+	app := New()
+	app.Host(":tenant.example.com").Mount("/admin", admin)
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "http://acme.example.com/admin/users/7", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "acme 7", string(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "http://localhost/admin/users/7", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+
+	// The host of the mounted app is kept
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "http://static.example.com/admin/logo", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "http://acme.example.com/admin/logo", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+
+	route := app.stack[methodInt(MethodGet)][0]
+	utils.AssertEqual(t, ":tenant.example.com", route.Host)
+	utils.AssertEqual(t, []string{"id", "tenant"}, route.Params)
+}
+
+// go test -run Test_Host_InvalidPattern
+func Test_Host_InvalidPattern(t *testing.T) {
+	t.Parallel()
+	for pattern, expected := range map[string]string{
+		"":                 "host: pattern must not be empty\n",
+		"example.com/path": "host: pattern must not contain a path example.com/path\n",
+	} {
+		func() {
+			defer func() {
+				utils.AssertEqual(t, expected, fmt.Sprintf("%v", recover()))
+			}()
+			New().Host(pattern)
+		}()
+	}
+}
+
+// go test -v -run=^$ -bench=Benchmark_Host_Match -benchmem -count=4
+func Benchmark_Host_Match(b *testing.B) {
+	host := parseHost(":tenant.example.com")
+	var params [maxParams]string
+	var match bool
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		match = host.match("acme.example.com", 0, &params)
+	}
+	utils.AssertEqual(b, true, match)
+	utils.AssertEqual(b, "acme", params[0])
+}
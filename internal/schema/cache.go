@@ -17,7 +17,7 @@ var errInvalidPath = errors.New("schema: invalid path")
 // newCache returns a new cache.
 func newCache() *cache {
 	c := cache{
-		m:       make(map[reflect.Type]*structInfo),
+		m:       make(map[cacheKey]*structInfo),
 		regconv: make(map[reflect.Type]Converter),
 		tag:     "schema",
 	}
@@ -27,11 +27,17 @@ func newCache() *cache {
 // cache caches meta-data about a struct.
 type cache struct {
 	l       sync.RWMutex
-	m       map[reflect.Type]*structInfo
+	m       map[cacheKey]*structInfo
 	regconv map[reflect.Type]Converter
 	tag     string
 }
 
+// cacheKey identifies the meta-data of a struct, the aliases depend on the tag.
+type cacheKey struct {
+	t   reflect.Type
+	tag string
+}
+
 // registerConverter registers a converter function for a custom type.
 func (c *cache) registerConverter(value interface{}, converterFunc Converter) {
 	c.regconv[reflect.TypeOf(value)] = converterFunc
@@ -114,12 +120,12 @@ func (c *cache) parsePath(p string, t reflect.Type) ([]pathPart, error) {
 // get returns a cached structInfo, creating it if necessary.
 func (c *cache) get(t reflect.Type) *structInfo {
 	c.l.RLock()
-	info := c.m[t]
+	info := c.m[cacheKey{t, c.tag}]
 	c.l.RUnlock()
 	if info == nil {
 		info = c.create(t, "")
 		c.l.Lock()
-		c.m[t] = info
+		c.m[cacheKey{t, c.tag}] = info
 		c.l.Unlock()
 	}
 	return info
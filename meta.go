@@ -0,0 +1,159 @@
+package lightning
+
+import (
+	"reflect"
+)
+
+// RouteMeta holds the documentation of a route, it's used by the openapi package
+type RouteMeta struct {
+	Summary   string               `json:"summary,omitempty"` // Short summary of the route
+	Tags      []string             `json:"tags,omitempty"`    // Tags to group the routes
+	Request   reflect.Type         `json:"-"`                 // Type of the params, the query, the headers and the body of the request
+	Responses map[int]reflect.Type `json:"-"`                 // Types of the response bodies by status code, nil for an empty body
+}
+
+// IsMiddleware returns true for the routes of Use and Static, they match all paths with their prefix
+func (r *Route) IsMiddleware() bool {
+	return r.use
+}
+
+// PathSegment is a constant part or a parameter of the parsed route path, it's used by the openapi package
+type PathSegment struct {
+	Const       string   // Constant part of the path, empty for parameters
+	Param       string   // Name of the parameter, wildcards and plus parameters are named "*1" or "+1"
+	Optional    bool     // The parameter can be empty, e.g. ":id?" or "*"
+	Greedy      bool     // The parameter can contain slashes, e.g. "*" or "+"
+	Constraints []string // Lower case names of the parameter constraints, e.g. "int" or "regex"
+}
+
+// PathVariants returns the segments of the route path as the router parses them. Paths with optional groups
+// like "/docs(/:lang)?" have a variant for every combination of the groups, the most specific variant comes first.
+func (r *Route) PathVariants() [][]PathSegment {
+	if len(r.rawParser.variants) == 0 {
+		return [][]PathSegment{pathSegments(r.rawParser.segs)}
+	}
+	variants := make([][]PathSegment, len(r.rawParser.variants))
+	for i := range r.rawParser.variants {
+		variants[i] = pathSegments(r.rawParser.variants[i].parser.segs)
+	}
+	return variants
+}
+
+// pathSegments converts the route segments to path segments
+func pathSegments(segs []*routeSegment) []PathSegment {
+	result := make([]PathSegment, len(segs))
+	for i, seg := range segs {
+		if !seg.IsParam {
+			result[i] = PathSegment{Const: seg.Const}
+			continue
+		}
+		result[i] = PathSegment{Param: seg.ParamName, Optional: seg.IsOptional, Greedy: seg.IsGreedy}
+		for _, constraint := range seg.Constraints {
+			for name, definition := range constraintDefinitions {
+				if definition.id == constraint.ID {
+					result[i].Constraints = append(result[i].Constraints, name)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// updateLatestRoute changes the latest registered route. Running requests can read the route
+// once the tree is built, so the change is made to a copy which replaces the route in the stack.
+func (app *App) updateLatestRoute(fn func(route *Route)) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	latestRoute.mu.Lock()
+	defer latestRoute.mu.Unlock()
+
+	route := latestRoute.route
+	if route == nil {
+		return
+	}
+	if !app.treeBuilt {
+		fn(route)
+		return
+	}
+	updated := *route
+	fn(&updated)
+	for m := range app.stack {
+		for i := range app.stack[m] {
+			if app.stack[m][i] == route {
+				app.stack[m][i] = &updated
+				app.routesRefreshed = true
+			}
+		}
+	}
+	latestRoute.route = &updated
+	app.buildTree()
+}
+
+// Summary sets the summary of the latest registered route.
+//  app.Get("/users/:id", handler).Summary("Get a user").Tags("users")
+func (app *App) Summary(summary string) Router {
+	app.updateLatestRoute(func(route *Route) {
+		route.Meta.Summary = summary
+	})
+	return app
+}
+
+// Tags adds tags to the latest registered route.
+func (app *App) Tags(tags ...string) Router {
+	app.updateLatestRoute(func(route *Route) {
+		current := route.Meta.Tags
+		route.Meta.Tags = append(current[:len(current):len(current)], tags...)
+	})
+	return app
+}
+
+// RequestType sets the type of the request of the latest registered route, fields with a
+// query or reqHeader tag are parameters and the other fields are the body of the request.
+//  app.Post("/users", handler).RequestType(CreateUser{})
+func (app *App) RequestType(v interface{}) Router {
+	app.updateLatestRoute(func(route *Route) {
+		route.Meta.Request = reflect.TypeOf(v)
+	})
+	return app
+}
+
+// ResponseType adds a status code and the type of its response body to the latest registered route,
+// nil is used for responses without a body.
+//  app.Get("/users/:id", handler).ResponseType(lightning.StatusOK, User{}).ResponseType(lightning.StatusNotFound, nil)
+func (app *App) ResponseType(status int, v interface{}) Router {
+	app.updateLatestRoute(func(route *Route) {
+		// Copy the map, because it's shared with the copies of the route
+		responses := make(map[int]reflect.Type, len(route.Meta.Responses)+1)
+		for code, typ := range route.Meta.Responses {
+			responses[code] = typ
+		}
+		responses[status] = reflect.TypeOf(v)
+		route.Meta.Responses = responses
+	})
+	return app
+}
+
+// Summary sets the summary of the latest registered route.
+func (grp *Group) Summary(summary string) Router {
+	_ = grp.app.Summary(summary)
+	return grp
+}
+
+// Tags adds tags to the latest registered route.
+func (grp *Group) Tags(tags ...string) Router {
+	_ = grp.app.Tags(tags...)
+	return grp
+}
+
+// RequestType sets the type of the request of the latest registered route.
+func (grp *Group) RequestType(v interface{}) Router {
+	_ = grp.app.RequestType(v)
+	return grp
+}
+
+// ResponseType adds a status code and the type of its response body to the latest registered route.
+func (grp *Group) ResponseType(status int, v interface{}) Router {
+	_ = grp.app.ResponseType(status, v)
+	return grp
+}
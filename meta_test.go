@@ -0,0 +1,39 @@
+package lightning
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// go test -run Test_Route_Meta
+func Test_Route_Meta(t *testing.T) {
+	t.Parallel()
+	type user struct {
+		Name string `json:"name"`
+	}
+	app := New()
+	app.Use(testEmptyHandler)
+	app.Add(MethodPost, "/users", testEmptyHandler).
+		Summary("Create a user").
+		Tags("users").Tags("admin").
+		RequestType(&user{}).
+		ResponseType(StatusCreated, user{}).
+		ResponseType(StatusBadRequest, nil)
+	app.Group("/api").Add(MethodGet, "/users", testEmptyHandler).Summary("List users")
+
+	route := app.Stack()[app.methodInt(MethodPost)][1]
+	utils.AssertEqual(t, false, route.IsMiddleware())
+	utils.AssertEqual(t, true, app.Stack()[app.methodInt(MethodPost)][0].IsMiddleware())
+	utils.AssertEqual(t, "Create a user", route.Meta.Summary)
+	utils.AssertEqual(t, []string{"users", "admin"}, route.Meta.Tags)
+	utils.AssertEqual(t, reflect.TypeOf(&user{}), route.Meta.Request)
+	utils.AssertEqual(t, map[int]reflect.Type{StatusCreated: reflect.TypeOf(user{}), StatusBadRequest: nil}, route.Meta.Responses)
+	utils.AssertEqual(t, "List users", app.Stack()[app.methodInt(MethodGet)][1].Meta.Summary)
+
+	// Mounted apps keep the metadata of their routes
+	parent := New()
+	parent.Mount("/v1", app)
+	utils.AssertEqual(t, "Create a user", parent.Stack()[parent.methodInt(MethodPost)][1].Meta.Summary)
+}
@@ -0,0 +1,43 @@
+package decompress
+
+import (
+	"github.com/ikidev/lightning"
+)
+
+// Config defines the config for middleware.
+type Config struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(req *lightning.Request, res *lightning.Response) bool
+
+	// Limit is the maximum size of the decompressed body, bigger bodies are
+	// rejected with 413 Request Entity Too Large. It also limits the size of
+	// a compressed body which is streamed.
+	//
+	// Optional. Default: 4 * 1024 * 1024
+	Limit int
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Next:  nil,
+	Limit: lightning.DefaultBodyLimit,
+}
+
+// Helper function to set default values
+func configDefault(config ...Config) Config {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Limit <= 0 {
+		cfg.Limit = ConfigDefault.Limit
+	}
+	return cfg
+}
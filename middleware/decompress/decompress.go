@@ -0,0 +1,121 @@
+package decompress
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ikidev/lightning"
+	"github.com/ikidev/lightning/internal/bytebufferpool"
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// supportedEncodings is sent in the Accept-Encoding header of a 415 response
+const supportedEncodings = "gzip, deflate, br"
+
+// errTooLarge is returned by the limitWriter if the limit is exceeded
+var errTooLarge = errors.New("decompress: body exceeds the limit")
+
+// New creates a new middleware handler
+func New(config ...Config) lightning.Handler {
+	// Set default config
+	cfg := configDefault(config...)
+
+	// Return new handler
+	return func(req *lightning.Request, res *lightning.Response) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(req, res) {
+			return req.Next()
+		}
+
+		fctx := req.FastHTTPContext()
+		header := strings.TrimSpace(utils.UnsafeString(fctx.Request.Header.Peek(lightning.HeaderContentEncoding)))
+		if header == "" {
+			return req.Next()
+		}
+
+		// The encodings are listed in the order in which they were applied
+		encodings := strings.Split(utils.ToLower(header), ",")
+		for i := range encodings {
+			encodings[i] = strings.TrimSpace(encodings[i])
+			switch encodings[i] {
+			case "gzip", "x-gzip", "deflate", "br", "identity":
+			default:
+				res.Header.Set(lightning.HeaderAcceptEncoding, supportedEncodings)
+				return lightning.ErrUnsupportedMediaType
+			}
+		}
+
+		body, err := compressedBody(fctx, cfg.Limit)
+		if err != nil {
+			return err
+		}
+
+		buf := bytebufferpool.Get()
+		defer bytebufferpool.Put(buf)
+		for i := len(encodings) - 1; i >= 0; i-- {
+			if encodings[i] == "identity" {
+				continue
+			}
+			buf.Reset()
+			if err = decode(&limitWriter{buf: buf, limit: cfg.Limit}, encodings[i], body); err != nil {
+				if err == errTooLarge {
+					return lightning.ErrRequestEntityTooLarge
+				}
+				return lightning.NewError(lightning.StatusBadRequest, "Invalid "+encodings[i]+" body")
+			}
+			body = append(body[:0], buf.B...)
+		}
+
+		// The handlers see a plain body, so BodyParser, FormValue and MultipartForm decode it
+		fctx.Request.Header.Del(lightning.HeaderContentEncoding)
+		fctx.Request.SetBody(body)
+
+		// Continue stack
+		return req.Next()
+	}
+}
+
+// compressedBody returns a copy of the compressed body, a streamed body is read up to the limit
+func compressedBody(fctx *fasthttp.RequestCtx, limit int) ([]byte, error) {
+	stream := fctx.RequestBodyStream()
+	if stream == nil {
+		return append([]byte(nil), fctx.Request.Body()...), nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(stream, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > limit {
+		return nil, lightning.ErrRequestEntityTooLarge
+	}
+	return body, nil
+}
+
+// decode writes the decoded body to w
+func decode(w io.Writer, encoding string, body []byte) (err error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		_, err = fasthttp.WriteGunzip(w, body)
+	case "deflate":
+		_, err = fasthttp.WriteInflate(w, body)
+	case "br":
+		_, err = fasthttp.WriteUnbrotli(w, body)
+	}
+	return err
+}
+
+// limitWriter stops the decompression as soon as the output exceeds the limit
+type limitWriter struct {
+	buf   *bytebufferpool.ByteBuffer
+	limit int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(w.buf.B)+len(p) > w.limit {
+		return 0, errTooLarge
+	}
+	return w.buf.Write(p)
+}
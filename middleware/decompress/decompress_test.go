@@ -0,0 +1,173 @@
+package decompress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ikidev/lightning"
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// compressedRequest creates a POST request with the body compressed by the encodings in order
+func compressedRequest(contentType string, body []byte, encodings ...string) *http.Request {
+	for _, encoding := range encodings {
+		switch encoding {
+		case "gzip":
+			body = fasthttp.AppendGzipBytes(nil, body)
+		case "deflate":
+			body = fasthttp.AppendDeflateBytes(nil, body)
+		case "br":
+			body = fasthttp.AppendBrotliBytes(nil, body)
+		}
+	}
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+	req.Header.Set(lightning.HeaderContentType, contentType)
+	req.Header.Set(lightning.HeaderContentEncoding, strings.Join(encodings, ", "))
+	return req
+}
+
+// go test -run Test_Decompress
+func Test_Decompress(t *testing.T) {
+	app := lightning.New()
+	app.Use(New())
+	app.Post("/", func(req *lightning.Request, res *lightning.Response) error {
+		u := struct {
+			Name string `json:"name"`
+		}{}
+		if err := req.Ctx().BodyParser(&u); err != nil {
+			return err
+		}
+		return res.String(u.Name + "," + req.Header.Get(lightning.HeaderContentEncoding))
+	})
+
+	for _, encodings := range [][]string{{"gzip"}, {"deflate"}, {"br"}, {"identity"}, {"deflate", "gzip"}} {
+		resp, err := app.Test(compressedRequest(lightning.MIMEApplicationJSON, []byte(`{"name":"john"}`), encodings...))
+		utils.AssertEqual(t, nil, err, encodings[0])
+		utils.AssertEqual(t, 200, resp.StatusCode, encodings[0])
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, encodings[0])
+		utils.AssertEqual(t, "john,", string(body), encodings[0])
+	}
+}
+
+// go test -run Test_Decompress_Form
+func Test_Decompress_Form(t *testing.T) {
+	app := lightning.New()
+	app.Use(New())
+	app.Post("/", func(req *lightning.Request, res *lightning.Response) error {
+		if _, err := req.MultipartForm(); err == nil {
+			file, err := req.FormFile("document")
+			if err != nil {
+				return err
+			}
+			return res.String(req.FormValue("name") + "," + file.Filename)
+		}
+		return res.String(req.FormValue("name"))
+	})
+
+	resp, err := app.Test(compressedRequest(lightning.MIMEApplicationForm, []byte("name=john"), "gzip"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 200, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john", string(body))
+
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	utils.AssertEqual(t, nil, w.WriteField("name", "john"))
+	file, err := w.CreateFormFile("document", "cv.pdf")
+	utils.AssertEqual(t, nil, err)
+	_, err = file.Write([]byte("%PDF"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, w.Close())
+
+	resp, err = app.Test(compressedRequest(w.FormDataContentType(), form.Bytes(), "br"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 200, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john,cv.pdf", string(body))
+}
+
+// go test -run Test_Decompress_Limit
+func Test_Decompress_Limit(t *testing.T) {
+	app := lightning.New()
+	app.Use(New(Config{Limit: 1024}))
+	app.Post("/", func(req *lightning.Request, res *lightning.Response) error {
+		return res.Bytes(req.Body())
+	})
+
+	resp, err := app.Test(compressedRequest(lightning.MIMETextPlain, bytes.Repeat([]byte("a"), 1024), "gzip"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 200, resp.StatusCode)
+
+	// a small body which decompresses beyond the limit
+	resp, err = app.Test(compressedRequest(lightning.MIMETextPlain, bytes.Repeat([]byte("a"), 1024*1024), "gzip"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 413, resp.StatusCode)
+}
+
+// go test -run Test_Decompress_Stream
+func Test_Decompress_Stream(t *testing.T) {
+	app := lightning.New(lightning.Config{StreamRequestBody: true, BodyLimit: 1024})
+	app.Use(New())
+	app.Post("/", func(req *lightning.Request, res *lightning.Response) error {
+		return res.String(strconv.Itoa(len(req.Body())))
+	})
+
+	resp, err := app.Test(compressedRequest(lightning.MIMETextPlain, bytes.Repeat([]byte("a"), 64*1024), "deflate"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 200, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "65536", string(body))
+}
+
+// go test -run Test_Decompress_Invalid
+func Test_Decompress_Invalid(t *testing.T) {
+	app := lightning.New()
+	app.Use(New())
+	app.Post("/", func(req *lightning.Request, res *lightning.Response) error {
+		return res.Bytes(req.Body())
+	})
+
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte("hello")))
+	req.Header.Set(lightning.HeaderContentEncoding, "compress")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 415, resp.StatusCode)
+	utils.AssertEqual(t, "gzip, deflate, br", resp.Header.Get(lightning.HeaderAcceptEncoding))
+
+	req = httptest.NewRequest("POST", "/", bytes.NewReader([]byte("hello")))
+	req.Header.Set(lightning.HeaderContentEncoding, "gzip")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 400, resp.StatusCode)
+}
+
+// go test -run Test_Decompress_Next
+func Test_Decompress_Next(t *testing.T) {
+	app := lightning.New()
+	app.Use(New(Config{
+		Next: func(_ *lightning.Request, _ *lightning.Response) bool {
+			return true
+		},
+	}))
+	app.Post("/", func(req *lightning.Request, res *lightning.Response) error {
+		return res.String(req.Header.Get(lightning.HeaderContentEncoding))
+	})
+
+	resp, err := app.Test(compressedRequest(lightning.MIMETextPlain, []byte("hello"), "gzip"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 200, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "gzip", string(body))
+}
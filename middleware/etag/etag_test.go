@@ -231,7 +231,7 @@ func Test_ETag_CustomEtagPut(t *testing.T) {
 	app.Use(New())
 
 	app.Put("/", func(req *lightning.Request, res *lightning.Response) error {
-		req.Header.Set(lightning.HeaderETag, `"custom"`)
+		res.Header.Set(lightning.HeaderETag, `"custom"`)
 		if !bytes.Equal(req.Ctx().Request().Header.Peek(lightning.HeaderIfMatch), []byte(`"custom"`)) {
 			return res.Status(lightning.StatusPreconditionFailed).Send()
 		}
@@ -1,6 +1,7 @@
 package recovery
 
 import (
+	"io/ioutil"
 	"net/http/httptest"
 	"testing"
 
@@ -56,3 +57,28 @@ func Test_Recover_EnableStackTrace(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, lightning.StatusInternalServerError, resp.StatusCode)
 }
+
+// go test -run Test_Recover_Problem
+func Test_Recover_Problem(t *testing.T) {
+	app := lightning.New(lightning.Config{ErrorHandler: lightning.ProblemErrorHandler})
+	app.Use(New())
+
+	app.Get("/panic", func(_ *lightning.Request, _ *lightning.Response) error {
+		panic("database password is wrong")
+	})
+	app.Get("/problem", func(_ *lightning.Request, _ *lightning.Response) error {
+		panic(lightning.NewProblem(lightning.StatusServiceUnavailable, "maintenance"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/panic", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, lightning.StatusInternalServerError, resp.StatusCode)
+	utils.AssertEqual(t, lightning.MIMEApplicationProblemJSON, resp.Header.Get(lightning.HeaderContentType))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"instance":"/panic","status":500,"title":"Internal Server Error","type":"about:blank"}`, string(body))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/problem", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, lightning.StatusServiceUnavailable, resp.StatusCode)
+}
@@ -16,7 +16,7 @@ func New(config ...Config) lightning.Handler {
 			return req.Next()
 		}
 		// Get id from request, else we generate one
-		rid := res.Header.Get(cfg.Header, cfg.Generator())
+		rid := req.Header.Get(cfg.Header, cfg.Generator())
 
 		// Set new id to response header
 		res.Header.Set(cfg.Header, rid)
@@ -51,7 +51,7 @@ func Test_Timeout_Panic(t *testing.T) {
 	app := lightning.New(lightning.Config{DisableStartupMessage: true})
 
 	app.Get("/panic", recovery.New(), New(func(req *lightning.Request, res *lightning.Response) error {
-		req.Header.Set("dummy", "this should not be here")
+		res.Header.Set("dummy", "this should not be here")
 		panic("panic in timeout handler")
 	}, 5*time.Millisecond))
 
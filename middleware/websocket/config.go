@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"compress/flate"
+
+	"github.com/ikidev/lightning"
+)
+
+// Config defines the config for middleware.
+type Config struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(req *lightning.Request, res *lightning.Response) bool
+
+	// Origins are the allowed values of the Origin header, "*" allows all origins.
+	// If it's empty, only requests without an Origin header or from the same host are allowed.
+	//
+	// Optional. Default: nil
+	Origins []string
+
+	// CheckOrigin defines a function to allow the origin of the request, it replaces the Origins check.
+	//
+	// Optional. Default: nil
+	CheckOrigin func(req *lightning.Request) bool
+
+	// Subprotocols are the supported subprotocols in the order of preference,
+	// the first one which is requested by the client is selected.
+	//
+	// Optional. Default: nil
+	Subprotocols []string
+
+	// EnableCompression negotiates the per-message deflate extension of RFC 7692.
+	//
+	// Optional. Default: false
+	EnableCompression bool
+
+	// CompressionLevel is the flate level of the compressed messages.
+	//
+	// Optional. Default: flate.BestSpeed
+	CompressionLevel int
+
+	// ReadLimit is the maximum size of a message in bytes, larger messages close the connection
+	// with CloseMessageTooBig.
+	//
+	// Optional. Default: 1MB
+	ReadLimit int64
+
+	// ReadBufferSize and WriteBufferSize are the sizes of the I/O buffers in bytes.
+	//
+	// Optional. Default: 4096
+	ReadBufferSize  int
+	WriteBufferSize int
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Next:             nil,
+	CompressionLevel: flate.BestSpeed,
+	ReadLimit:        1024 * 1024,
+	ReadBufferSize:   4096,
+	WriteBufferSize:  4096,
+}
+
+// Helper function to set default values
+func configDefault(config ...Config) Config {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.CompressionLevel == 0 {
+		cfg.CompressionLevel = ConfigDefault.CompressionLevel
+	}
+	if cfg.ReadLimit <= 0 {
+		cfg.ReadLimit = ConfigDefault.ReadLimit
+	}
+	if cfg.ReadBufferSize <= 0 {
+		cfg.ReadBufferSize = ConfigDefault.ReadBufferSize
+	}
+	if cfg.WriteBufferSize <= 0 {
+		cfg.WriteBufferSize = ConfigDefault.WriteBufferSize
+	}
+	return cfg
+}
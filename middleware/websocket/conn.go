@@ -0,0 +1,451 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// The message types of RFC 6455 section 11.8
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// The close codes of RFC 6455 section 11.7
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+// Frame header bits
+const (
+	finalBit = 0x80
+	rsv1Bit  = 0x40
+	rsv2Bit  = 0x20
+	rsv3Bit  = 0x10
+	maskBit  = 0x80
+
+	maxControlPayload = 125
+)
+
+// deflateTail completes a compressed message, see RFC 7692 section 7.2.2
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+// ErrCloseSent is returned when a message is written after the close frame
+var ErrCloseSent = errors.New("websocket: close frame already sent")
+
+// CloseError is returned by ReadMessage when the connection is closed
+type CloseError struct {
+	Code int
+	Text string
+}
+
+// Error returns the close code and the reason
+func (e *CloseError) Error() string {
+	if e.Text == "" {
+		return "websocket: close " + strconv.Itoa(e.Code)
+	}
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// Conn is an upgraded WebSocket connection, a single goroutine can read while others write
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	writeMu   sync.Mutex
+	bw        *bufio.Writer
+	closeSent bool
+
+	readLimit   int64
+	readErr     error
+	compress    bool
+	level       int
+	subprotocol string
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+
+	params map[string]string
+	query  map[string]string
+	locals map[string]interface{}
+}
+
+// init attaches the hijacked connection
+func (c *Conn) init(conn net.Conn, readBufferSize, writeBufferSize int) {
+	c.conn = conn
+	c.br = bufio.NewReaderSize(conn, readBufferSize)
+	c.bw = bufio.NewWriterSize(conn, writeBufferSize)
+}
+
+// Params returns the route param of the upgrade request
+func (c *Conn) Params(key string, defaultValue ...string) string {
+	if value, ok := c.params[key]; ok && value != "" {
+		return value
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
+// Query returns the query param of the upgrade request
+func (c *Conn) Query(key string, defaultValue ...string) string {
+	if value, ok := c.query[key]; ok && value != "" {
+		return value
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
+// Locals returns the value which was stored in the locals of the upgrade request, e.g. by a middleware
+func (c *Conn) Locals(key string) interface{} {
+	return c.locals[key]
+}
+
+// Subprotocol returns the negotiated subprotocol
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// LocalAddr returns the local network address
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline for the next reads
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for the next writes
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit sets the maximum size of a message in bytes
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPingHandler sets the handler of ping messages, the default handler answers with a pong
+func (c *Conn) SetPingHandler(handler func(data []byte) error) {
+	c.pingHandler = handler
+}
+
+// SetPongHandler sets the handler of pong messages, e.g. to extend the read deadline
+func (c *Conn) SetPongHandler(handler func(data []byte) error) {
+	c.pongHandler = handler
+}
+
+// ReadMessage reads the next text or binary message, fragmented messages are joined and
+// control messages are handled in between. A *CloseError is returned after the connection is closed.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	compressed := false
+	for {
+		final, rsv1, opcode, payload, err := c.readFrame(int64(len(p)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err = c.handlePing(payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				if err = c.pongHandler(payload); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType, compressed = opcode, rsv1
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		}
+		p = append(p, payload...)
+		if final {
+			break
+		}
+	}
+	if compressed {
+		if p, err = c.decompress(p); err != nil {
+			return 0, nil, err
+		}
+	}
+	if messageType == TextMessage && !utf8.Valid(p) {
+		return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid utf-8")
+	}
+	return messageType, p, nil
+}
+
+// readFrame reads and validates a single frame, read is the size of the message so far
+func (c *Conn) readFrame(read int64) (final, rsv1 bool, opcode int, payload []byte, err error) {
+	var header [14]byte
+	if _, err = io.ReadFull(c.br, header[:2]); err != nil {
+		return false, false, 0, nil, c.abort(err)
+	}
+	final, rsv1, opcode = header[0]&finalBit != 0, header[0]&rsv1Bit != 0, int(header[0]&0x0f)
+	masked, length := header[1]&maskBit != 0, int64(header[1]&0x7f)
+
+	switch {
+	case header[0]&(rsv2Bit|rsv3Bit) != 0:
+		return false, false, 0, nil, c.fail(CloseProtocolError, "unexpected reserved bits")
+	case rsv1 && (!c.compress || (opcode != TextMessage && opcode != BinaryMessage)):
+		return false, false, 0, nil, c.fail(CloseProtocolError, "unexpected reserved bits")
+	case opcode >= CloseMessage && opcode <= PongMessage:
+		if !final || length > maxControlPayload {
+			return false, false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+		}
+	case opcode > BinaryMessage:
+		return false, false, 0, nil, c.fail(CloseProtocolError, "unknown opcode "+strconv.Itoa(opcode))
+	}
+	// Clients must mask their frames, see RFC 6455 section 5.1
+	if !masked {
+		return false, false, 0, nil, c.fail(CloseProtocolError, "frame is not masked")
+	}
+
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(c.br, header[2:4]); err != nil {
+			return false, false, 0, nil, c.abort(err)
+		}
+		length = int64(binary.BigEndian.Uint16(header[2:4]))
+	case 127:
+		if _, err = io.ReadFull(c.br, header[2:10]); err != nil {
+			return false, false, 0, nil, c.abort(err)
+		}
+		length = int64(binary.BigEndian.Uint64(header[2:10]))
+		if length < 0 {
+			return false, false, 0, nil, c.fail(CloseProtocolError, "invalid payload length")
+		}
+	}
+	if opcode < CloseMessage && read+length > c.readLimit {
+		return false, false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return false, false, 0, nil, c.abort(err)
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return false, false, 0, nil, c.abort(err)
+	}
+	for i := range payload {
+		payload[i] ^= mask[i&3]
+	}
+	return final, rsv1, opcode, payload, nil
+}
+
+// handlePing calls the ping handler, the default handler answers with a pong
+func (c *Conn) handlePing(data []byte) error {
+	if c.pingHandler != nil {
+		return c.pingHandler(data)
+	}
+	if err := c.writeFrame(PongMessage, false, data); err != nil && err != ErrCloseSent {
+		return c.abort(err)
+	}
+	return nil
+}
+
+// handleClose answers the close frame of the client and closes the connection
+func (c *Conn) handleClose(payload []byte) error {
+	code, text := CloseNoStatusReceived, ""
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		code, text = int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+		if !validCloseCode(code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(text) {
+			return c.fail(CloseInvalidFramePayloadData, "invalid utf-8")
+		}
+	}
+	// Echo the close code, see RFC 6455 section 5.5.1
+	if code == CloseNoStatusReceived {
+		_ = c.writeFrame(CloseMessage, false, nil)
+	} else {
+		_ = c.writeFrame(CloseMessage, false, payload[:2])
+	}
+	_ = c.conn.Close()
+	c.readErr = &CloseError{Code: code, Text: text}
+	return c.readErr
+}
+
+// fail closes the connection because of a protocol violation of the client
+func (c *Conn) fail(code int, text string) error {
+	_ = c.WriteClose(code, text)
+	_ = c.conn.Close()
+	c.readErr = &CloseError{Code: code, Text: text}
+	return c.readErr
+}
+
+// abort closes the connection after an I/O error
+func (c *Conn) abort(err error) error {
+	_ = c.conn.Close()
+	c.readErr = &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+	return c.readErr
+}
+
+// WriteMessage writes a message of the given type, text and binary messages are compressed if negotiated
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+		if c.compress {
+			return c.writeFrame(messageType, true, c.deflate(data))
+		}
+	case CloseMessage, PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return errors.New("websocket: control message is too big")
+		}
+	default:
+		return errors.New("websocket: unknown message type " + strconv.Itoa(messageType))
+	}
+	return c.writeFrame(messageType, false, data)
+}
+
+// Ping sends a ping message, the client answers with a pong
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteMessage(PingMessage, data)
+}
+
+// WriteClose sends a close frame with the code and the reason, the client answers with a close frame.
+// Long reasons are shortened to the size of a control frame.
+func (c *Conn) WriteClose(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	if len(payload) > maxControlPayload {
+		// The reason must stay valid UTF-8, so it's cut in front of the rune which doesn't fit
+		end := maxControlPayload
+		for end > 2 && !utf8.RuneStart(payload[end]) {
+			end--
+		}
+		payload = payload[:end]
+	}
+	return c.writeFrame(CloseMessage, false, payload)
+}
+
+// Close sends a normal close frame if none was sent and closes the connection
+func (c *Conn) Close() error {
+	_ = c.WriteClose(CloseNormalClosure, "")
+	return c.conn.Close()
+}
+
+// writeFrame writes an unmasked frame and flushes it
+func (c *Conn) writeFrame(opcode int, rsv1 bool, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	var header [10]byte
+	header[0] = finalBit | byte(opcode)
+	if rsv1 {
+		header[0] |= rsv1Bit
+	}
+	n := 2
+	switch length := len(payload); {
+	case length <= maxControlPayload:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+		n = 4
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+		n = 10
+	}
+	if _, err := c.bw.Write(header[:n]); err != nil {
+		return err
+	}
+	if _, err := c.bw.Write(payload); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+// deflate compresses the message without the tail of the flush, see RFC 7692 section 7.2.1
+func (c *Conn) deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, c.level)
+	_, _ = w.Write(data)
+	_ = w.Flush()
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail[:4])
+}
+
+// decompress inflates the message, the limit applies to the decompressed size
+func (c *Conn) decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer r.Close()
+	p, err := ioutil.ReadAll(io.LimitReader(r, c.readLimit+1))
+	if err != nil {
+		return nil, c.fail(CloseInvalidFramePayloadData, "invalid compressed data")
+	}
+	if int64(len(p)) > c.readLimit {
+		return nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+	return p, nil
+}
+
+// validCloseCode checks if the code can be sent in a close frame, see RFC 6455 section 7.4
+func validCloseCode(code int) bool {
+	switch {
+	case code >= CloseNormalClosure && code <= CloseUnsupportedData:
+		return true
+	case code >= CloseInvalidFramePayloadData && code <= CloseInternalServerErr:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net"
+	"strings"
+
+	"github.com/ikidev/lightning"
+	"github.com/ikidev/lightning/utils"
+)
+
+// acceptGUID is appended to the key of the client to compute the accept key, see RFC 6455 section 1.3
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// New creates a new middleware handler which upgrades the request to a WebSocket connection
+// and calls the handler with it. Requests without an upgrade are rejected with 426 Upgrade Required.
+//  app.Get("/ws/:room", websocket.New(func(conn *websocket.Conn) {
+//  	for {
+//  		messageType, message, err := conn.ReadMessage()
+//  		if err != nil {
+//  			return
+//  		}
+//  		_ = conn.WriteMessage(messageType, message)
+//  	}
+//  }))
+func New(handler func(conn *Conn), config ...Config) lightning.Handler {
+	// Set default config
+	cfg := configDefault(config...)
+
+	// Return new handler
+	return func(req *lightning.Request, res *lightning.Response) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(req, res) {
+			return req.Next()
+		}
+
+		if !IsWebSocketUpgrade(req) {
+			return lightning.ErrUpgradeRequired
+		}
+		// Only version 13 of RFC 6455 is supported
+		if req.Header.Get(lightning.HeaderSecWebSocketVersion) != "13" {
+			res.Header.Set(lightning.HeaderSecWebSocketVersion, "13")
+			return lightning.ErrUpgradeRequired
+		}
+		key := req.Header.Get(lightning.HeaderSecWebSocketKey)
+		if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+			return lightning.NewError(lightning.StatusBadRequest, "websocket: invalid Sec-WebSocket-Key")
+		}
+		if !cfg.allowOrigin(req) {
+			return lightning.NewError(lightning.StatusForbidden, "websocket: origin not allowed")
+		}
+
+		// The request is released before the handler runs
+		conn := &Conn{
+			readLimit:   cfg.ReadLimit,
+			level:       cfg.CompressionLevel,
+			subprotocol: selectSubprotocol(req.Header.Get(lightning.HeaderSecWebSocketProtocol), cfg.Subprotocols),
+			compress:    cfg.EnableCompression && offersDeflate(req.Header.Get(lightning.HeaderSecWebSocketExtensions)),
+			params:      make(map[string]string),
+			query:       make(map[string]string),
+			locals:      make(map[string]interface{}),
+		}
+		for _, key := range req.Route().Params {
+			conn.params[key] = utils.CopyString(req.Param(key))
+		}
+		req.QueryArgs().VisitAll(func(key, val []byte) {
+			conn.query[string(key)] = string(val)
+		})
+		req.FastHTTPContext().VisitUserValues(func(key []byte, val interface{}) {
+			conn.locals[string(key)] = val
+		})
+
+		res.Header.Set(lightning.HeaderSecWebSocketAccept, acceptKey(key))
+		if conn.subprotocol != "" {
+			res.Header.Set(lightning.HeaderSecWebSocketProtocol, conn.subprotocol)
+		}
+		if conn.compress {
+			// The compression context is reset after every message on both sides
+			res.Header.Set(lightning.HeaderSecWebSocketExtensions, "permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+		}
+
+		return res.Upgrade("websocket", func(netConn net.Conn) {
+			conn.init(netConn, cfg.ReadBufferSize, cfg.WriteBufferSize)
+			defer conn.Close()
+			handler(conn)
+		})
+	}
+}
+
+// IsWebSocketUpgrade returns true if the client requests an upgrade to the WebSocket protocol
+func IsWebSocketUpgrade(req *lightning.Request) bool {
+	return req.Method() == lightning.MethodGet &&
+		hasToken(req.Header.Get(lightning.HeaderConnection), "upgrade") &&
+		hasToken(req.Header.Get(lightning.HeaderUpgrade), "websocket")
+}
+
+// allowOrigin checks the Origin header of the request
+func (cfg *Config) allowOrigin(req *lightning.Request) bool {
+	if cfg.CheckOrigin != nil {
+		return cfg.CheckOrigin(req)
+	}
+	origin := req.Header.Get(lightning.HeaderOrigin)
+	if len(cfg.Origins) == 0 {
+		// Same origin policy
+		if origin == "" {
+			return true
+		}
+		if i := strings.Index(origin, "://"); i != -1 {
+			origin = origin[i+3:]
+		}
+		return utils.EqualFold(origin, string(req.FastHTTPContext().Host()))
+	}
+	for _, allowed := range cfg.Origins {
+		if allowed == "*" || utils.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptKey computes the Sec-WebSocket-Accept header for the key of the client
+func acceptKey(key string) string {
+	h := sha1.New()
+	_, _ = h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// selectSubprotocol returns the first supported subprotocol which is requested by the client
+func selectSubprotocol(header string, supported []string) string {
+	for _, protocol := range supported {
+		if hasToken(header, protocol) {
+			return protocol
+		}
+	}
+	return ""
+}
+
+// offersDeflate checks if the client offers the per-message deflate extension
+func offersDeflate(header string) bool {
+	for _, extension := range strings.Split(header, ",") {
+		if name := strings.TrimSpace(strings.SplitN(extension, ";", 2)[0]); name == "permessage-deflate" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasToken checks if the comma separated header contains the token, case-insensitive
+func hasToken(header, token string) bool {
+	for _, value := range strings.Split(header, ",") {
+		if utils.EqualFold(strings.TrimSpace(value), token) {
+			return true
+		}
+	}
+	return false
+}
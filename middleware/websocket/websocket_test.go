@@ -0,0 +1,397 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ikidev/lightning"
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// testClient is a minimal WebSocket client for the tests
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+	resp *http.Response
+}
+
+// serve starts the app on an in-memory listener
+func serve(t *testing.T, app *lightning.App) *fasthttputil.InmemoryListener {
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		_ = app.Listener(ln)
+	}()
+	t.Cleanup(func() {
+		_ = app.Shutdown()
+	})
+	return ln
+}
+
+// dial starts the app and performs the handshake
+func dial(t *testing.T, app *lightning.App, path string, headers map[string]string) *testClient {
+	return handshake(t, serve(t, app), path, headers)
+}
+
+// handshake sends the upgrade request with the headers, they replace the default headers
+func handshake(t *testing.T, ln *fasthttputil.InmemoryListener, path string, headers map[string]string) *testClient {
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+
+	header := http.Header{}
+	header.Set(lightning.HeaderHost, "example.com")
+	header.Set(lightning.HeaderConnection, "Upgrade")
+	header.Set(lightning.HeaderUpgrade, "websocket")
+	header.Set(lightning.HeaderSecWebSocketVersion, "13")
+	header.Set(lightning.HeaderSecWebSocketKey, "dGhlIHNhbXBsZSBub25jZQ==")
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	var req bytes.Buffer
+	req.WriteString("GET " + path + " HTTP/1.1\r\n")
+	_ = header.Write(&req)
+	req.WriteString("\r\n")
+	_, err = conn.Write(req.Bytes())
+	utils.AssertEqual(t, nil, err)
+
+	client := &testClient{t: t, conn: conn, br: bufio.NewReader(conn)}
+	client.resp, err = http.ReadResponse(client.br, nil)
+	utils.AssertEqual(t, nil, err)
+	return client
+}
+
+// write sends a masked frame
+func (c *testClient) write(first byte, payload []byte) {
+	frame := []byte{first, 0x80}
+	switch {
+	case len(payload) <= 125:
+		frame[1] |= byte(len(payload))
+	case len(payload) <= 0xffff:
+		frame[1] |= 126
+		frame = append(frame, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		frame[1] |= 127
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(payload)))
+		frame = append(frame, length[:]...)
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	utils.AssertEqual(c.t, nil, err)
+}
+
+// read receives an unmasked frame of the server
+func (c *testClient) read() (first byte, payload []byte) {
+	var header [2]byte
+	_, err := io.ReadFull(c.br, header[:])
+	utils.AssertEqual(c.t, nil, err)
+	utils.AssertEqual(c.t, byte(0), header[1]&0x80)
+	length := int(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, _ = io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, _ = io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload = make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	utils.AssertEqual(c.t, nil, err)
+	return header[0], payload
+}
+
+// readClose receives a close frame and returns its code
+func (c *testClient) readClose() int {
+	first, payload := c.read()
+	utils.AssertEqual(c.t, byte(0x80|CloseMessage), first)
+	if len(payload) < 2 {
+		return CloseNoStatusReceived
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func closePayload(code int, text string) []byte {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, text...)
+}
+
+func echoHandler(conn *Conn) {
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err = conn.WriteMessage(messageType, message); err != nil {
+			return
+		}
+	}
+}
+
+// go test -run Test_WebSocket_Echo
+func Test_WebSocket_Echo(t *testing.T) {
+	t.Parallel()
+	app := lightning.New(lightning.Config{DisableStartupMessage: true})
+	app.Use(func(req *lightning.Request, res *lightning.Response) error {
+		req.Locals("user", "john")
+		return req.Next()
+	})
+	app.Get("/ws/:room", New(func(conn *Conn) {
+		_ = conn.WriteMessage(TextMessage, []byte(conn.Params("room")+" "+conn.Query("token")+" "+conn.Locals("user").(string)))
+		echoHandler(conn)
+	}, Config{Subprotocols: []string{"graphql-ws", "chat"}}))
+
+	client := dial(t, app, "/ws/lobby?token=secret", map[string]string{lightning.HeaderSecWebSocketProtocol: "chat, superchat"})
+	utils.AssertEqual(t, lightning.StatusSwitchingProtocols, client.resp.StatusCode)
+	utils.AssertEqual(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", client.resp.Header.Get(lightning.HeaderSecWebSocketAccept))
+	utils.AssertEqual(t, "websocket", client.resp.Header.Get(lightning.HeaderUpgrade))
+	utils.AssertEqual(t, "chat", client.resp.Header.Get(lightning.HeaderSecWebSocketProtocol))
+	utils.AssertEqual(t, "", client.resp.Header.Get(lightning.HeaderSecWebSocketExtensions))
+
+	first, payload := client.read()
+	utils.AssertEqual(t, byte(0x80|TextMessage), first)
+	utils.AssertEqual(t, "lobby secret john", string(payload))
+
+	client.write(0x80|TextMessage, []byte("hello"))
+	first, payload = client.read()
+	utils.AssertEqual(t, byte(0x80|TextMessage), first)
+	utils.AssertEqual(t, "hello", string(payload))
+
+	large := bytes.Repeat([]byte{7}, 70000)
+	client.write(0x80|BinaryMessage, large)
+	first, payload = client.read()
+	utils.AssertEqual(t, byte(0x80|BinaryMessage), first)
+	utils.AssertEqual(t, large, payload)
+
+	// Fragmented message with a ping in between
+	client.write(TextMessage, []byte("frag"))
+	client.write(0x80|PingMessage, []byte("are you there?"))
+	client.write(continuationFrame, []byte("men"))
+	client.write(0x80|continuationFrame, []byte("ted"))
+	first, payload = client.read()
+	utils.AssertEqual(t, byte(0x80|PongMessage), first)
+	utils.AssertEqual(t, "are you there?", string(payload))
+	first, payload = client.read()
+	utils.AssertEqual(t, byte(0x80|TextMessage), first)
+	utils.AssertEqual(t, "fragmented", string(payload))
+
+	// Close handshake
+	client.write(0x80|CloseMessage, closePayload(CloseGoingAway, "bye"))
+	utils.AssertEqual(t, CloseGoingAway, client.readClose())
+}
+
+// go test -run Test_WebSocket_Close
+func Test_WebSocket_Close(t *testing.T) {
+	t.Parallel()
+	errs := make(chan error, 1)
+	app := lightning.New(lightning.Config{DisableStartupMessage: true})
+	app.Get("/", New(func(conn *Conn) {
+		_, _, err := conn.ReadMessage()
+		errs <- err
+	}))
+
+	client := dial(t, app, "/", nil)
+	client.write(0x80|CloseMessage, closePayload(4000, "custom"))
+	utils.AssertEqual(t, 4000, client.readClose())
+	err := <-errs
+	utils.AssertEqual(t, &CloseError{Code: 4000, Text: "custom"}, err)
+	utils.AssertEqual(t, "websocket: close 4000 custom", err.Error())
+}
+
+// go test -run Test_WebSocket_WriteClose_LongReason
+func Test_WebSocket_WriteClose_LongReason(t *testing.T) {
+	t.Parallel()
+	app := lightning.New(lightning.Config{DisableStartupMessage: true})
+	app.Get("/", New(func(conn *Conn) {
+		_ = conn.WriteClose(CloseGoingAway, strings.Repeat("ä", 100))
+	}))
+
+	// The reason is cut at a rune boundary, so it stays valid UTF-8
+	client := dial(t, app, "/", nil)
+	first, payload := client.read()
+	utils.AssertEqual(t, byte(0x80|CloseMessage), first)
+	utils.AssertEqual(t, 124, len(payload))
+	utils.AssertEqual(t, CloseGoingAway, int(binary.BigEndian.Uint16(payload)))
+	utils.AssertEqual(t, true, utf8.Valid(payload[2:]))
+	utils.AssertEqual(t, strings.Repeat("ä", 61), string(payload[2:]))
+}
+
+// go test -run Test_WebSocket_ProtocolErrors
+func Test_WebSocket_ProtocolErrors(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		write func(client *testClient)
+		code  int
+	}{
+		{name: "unmasked", code: CloseProtocolError, write: func(client *testClient) {
+			_, _ = client.conn.Write([]byte{0x80 | TextMessage, 2, 'h', 'i'})
+		}},
+		{name: "reserved bits", code: CloseProtocolError, write: func(client *testClient) {
+			client.write(0x80|rsv2Bit|TextMessage, []byte("hi"))
+		}},
+		{name: "compressed without negotiation", code: CloseProtocolError, write: func(client *testClient) {
+			client.write(0x80|rsv1Bit|TextMessage, []byte("hi"))
+		}},
+		{name: "unknown opcode", code: CloseProtocolError, write: func(client *testClient) {
+			client.write(0x80|3, []byte("hi"))
+		}},
+		{name: "fragmented control frame", code: CloseProtocolError, write: func(client *testClient) {
+			client.write(PingMessage, []byte("hi"))
+		}},
+		{name: "unexpected continuation", code: CloseProtocolError, write: func(client *testClient) {
+			client.write(0x80|continuationFrame, []byte("hi"))
+		}},
+		{name: "interrupted message", code: CloseProtocolError, write: func(client *testClient) {
+			client.write(TextMessage, []byte("hi"))
+			client.write(0x80|TextMessage, []byte("hi"))
+		}},
+		{name: "invalid utf-8", code: CloseInvalidFramePayloadData, write: func(client *testClient) {
+			client.write(0x80|TextMessage, []byte{0xff, 0xfe})
+		}},
+		{name: "invalid close code", code: CloseProtocolError, write: func(client *testClient) {
+			client.write(0x80|CloseMessage, closePayload(1005, ""))
+		}},
+		{name: "too big", code: CloseMessageTooBig, write: func(client *testClient) {
+			client.write(BinaryMessage, make([]byte, 60))
+			client.write(0x80|continuationFrame, make([]byte, 60))
+		}},
+	}
+
+	for _, tc := range testCases {
+		app := lightning.New(lightning.Config{DisableStartupMessage: true})
+		app.Get("/", New(echoHandler, Config{ReadLimit: 100}))
+		client := dial(t, app, "/", nil)
+		tc.write(client)
+		utils.AssertEqual(t, tc.code, client.readClose(), tc.name)
+	}
+}
+
+// go test -run Test_WebSocket_Compression
+func Test_WebSocket_Compression(t *testing.T) {
+	t.Parallel()
+	app := lightning.New(lightning.Config{DisableStartupMessage: true})
+	app.Get("/", New(echoHandler, Config{EnableCompression: true, ReadLimit: 1000}))
+
+	client := dial(t, app, "/", map[string]string{lightning.HeaderSecWebSocketExtensions: "permessage-deflate; client_max_window_bits"})
+	utils.AssertEqual(t, "permessage-deflate; server_no_context_takeover; client_no_context_takeover",
+		client.resp.Header.Get(lightning.HeaderSecWebSocketExtensions))
+
+	message := strings.Repeat("lightning ", 50)
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	_, _ = w.Write([]byte(message))
+	_ = w.Flush()
+	client.write(0x80|rsv1Bit|TextMessage, bytes.TrimSuffix(buf.Bytes(), []byte{0, 0, 0xff, 0xff}))
+
+	first, payload := client.read()
+	utils.AssertEqual(t, byte(0x80|rsv1Bit|TextMessage), first)
+	utils.AssertEqual(t, true, len(payload) < len(message))
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail)))
+	decompressed, err := ioutil.ReadAll(r)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, message, string(decompressed))
+
+	// The read limit applies to the decompressed message
+	buf.Reset()
+	w.Reset(&buf)
+	_, _ = w.Write(bytes.Repeat([]byte("a"), 2000))
+	_ = w.Flush()
+	client.write(0x80|rsv1Bit|BinaryMessage, bytes.TrimSuffix(buf.Bytes(), []byte{0, 0, 0xff, 0xff}))
+	utils.AssertEqual(t, CloseMessageTooBig, client.readClose())
+}
+
+// go test -run Test_WebSocket_Handshake
+func Test_WebSocket_Handshake(t *testing.T) {
+	t.Parallel()
+	app := lightning.New(lightning.Config{DisableStartupMessage: true})
+	app.Get("/", New(echoHandler))
+	app.Get("/origins", New(echoHandler, Config{Origins: []string{"https://app.example.com"}}))
+	app.Get("/check", New(echoHandler, Config{CheckOrigin: func(req *lightning.Request) bool {
+		return req.Query("allow") == "1"
+	}}))
+	app.Get("/next", New(echoHandler, Config{Next: func(req *lightning.Request, res *lightning.Response) bool {
+		return true
+	}}))
+
+	// Requests without an upgrade
+	resp, err := app.Test(httptest.NewRequest(lightning.MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, lightning.StatusUpgradeRequired, resp.StatusCode)
+
+	ln := serve(t, app)
+	testCases := []struct {
+		url     string
+		headers map[string]string
+		code    int
+	}{
+		{url: "/", headers: map[string]string{lightning.HeaderSecWebSocketVersion: "8"}, code: lightning.StatusUpgradeRequired},
+		{url: "/", headers: map[string]string{lightning.HeaderSecWebSocketKey: "short"}, code: lightning.StatusBadRequest},
+		{url: "/", headers: map[string]string{lightning.HeaderConnection: "keep-alive, upgrade", lightning.HeaderUpgrade: "WebSocket"}, code: lightning.StatusSwitchingProtocols},
+		{url: "/", headers: map[string]string{lightning.HeaderOrigin: "http://example.com"}, code: lightning.StatusSwitchingProtocols},
+		{url: "/", headers: map[string]string{lightning.HeaderOrigin: "http://evil.com"}, code: lightning.StatusForbidden},
+		{url: "/origins", headers: map[string]string{lightning.HeaderOrigin: "https://app.example.com"}, code: lightning.StatusSwitchingProtocols},
+		{url: "/origins", headers: map[string]string{lightning.HeaderOrigin: "http://example.com"}, code: lightning.StatusForbidden},
+		{url: "/check?allow=1", headers: map[string]string{lightning.HeaderOrigin: "http://evil.com"}, code: lightning.StatusSwitchingProtocols},
+		{url: "/check", code: lightning.StatusForbidden},
+		{url: "/next", code: lightning.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		client := handshake(t, ln, tc.url, tc.headers)
+		utils.AssertEqual(t, tc.code, client.resp.StatusCode, tc.url)
+		if tc.code == lightning.StatusUpgradeRequired {
+			utils.AssertEqual(t, "13", client.resp.Header.Get(lightning.HeaderSecWebSocketVersion))
+		}
+		_ = client.conn.Close()
+	}
+}
+
+// go test -run Test_IsWebSocketUpgrade
+func Test_IsWebSocketUpgrade(t *testing.T) {
+	t.Parallel()
+	app := lightning.New()
+	var upgrades []bool
+	app.All("/", func(req *lightning.Request, res *lightning.Response) error {
+		upgrades = append(upgrades, IsWebSocketUpgrade(req))
+		return nil
+	})
+
+	testCases := []struct {
+		method     string
+		connection string
+		upgrade    string
+	}{
+		{method: lightning.MethodGet, connection: "Upgrade", upgrade: "websocket"},
+		{method: lightning.MethodGet, connection: "keep-alive, upgrade", upgrade: "WebSocket"},
+		{method: lightning.MethodPost, connection: "Upgrade", upgrade: "websocket"},
+		{method: lightning.MethodGet, connection: "keep-alive", upgrade: "websocket"},
+		{method: lightning.MethodGet, connection: "Upgrade", upgrade: "h2c"},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, "/", nil)
+		req.Header.Set(lightning.HeaderConnection, tc.connection)
+		req.Header.Set(lightning.HeaderUpgrade, tc.upgrade)
+		_, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+	}
+	utils.AssertEqual(t, []bool{true, true, false, false, false}, upgrades)
+}
@@ -0,0 +1,432 @@
+package lightning
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ikidev/lightning/internal/msgp"
+)
+
+// msgPackField is an exported field of a struct which is encoded as a map entry
+type msgPackField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// msgPackFields caches the fields of the struct types
+var msgPackFields sync.Map
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	marshalerType   = reflect.TypeOf((*msgp.Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*msgp.Unmarshaler)(nil)).Elem()
+)
+
+// marshalMsgPack encodes v as MessagePack, msgp.Marshaler is used without reflection.
+// Other structs are encoded as maps, the keys are taken from the msgpack tag,
+// the json tag or the field name, so one struct works for JSON and MessagePack.
+func marshalMsgPack(v interface{}) ([]byte, error) {
+	if marshaler, ok := v.(msgp.Marshaler); ok {
+		return marshaler.MarshalMsg(nil)
+	}
+	return appendMsgPack(nil, reflect.ValueOf(v))
+}
+
+// unmarshalMsgPack decodes the MessagePack data into the value which v points to,
+// msgp.Unmarshaler is used without reflection
+func unmarshalMsgPack(data []byte, v interface{}) error {
+	if unmarshaler, ok := v.(msgp.Unmarshaler); ok {
+		_, err := unmarshaler.UnmarshalMsg(data)
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("msgpack: decode target must be a non-nil pointer")
+	}
+	_, err := decodeMsgPack(data, rv.Elem())
+	return err
+}
+
+func appendMsgPack(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return msgp.AppendNil(b), nil
+	}
+	if v.Type() == timeType {
+		return msgp.AppendTime(b, v.Interface().(time.Time)), nil
+	}
+	// the code generated by github.com/tinylib/msgp implements the marshaler with pointer receivers
+	if v.Type().Implements(marshalerType) && v.CanInterface() && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+		return v.Interface().(msgp.Marshaler).MarshalMsg(b)
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(marshalerType) && v.Addr().CanInterface() {
+		return v.Addr().Interface().(msgp.Marshaler).MarshalMsg(b)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return msgp.AppendNil(b), nil
+		}
+		return appendMsgPack(b, v.Elem())
+	case reflect.Bool:
+		return msgp.AppendBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return msgp.AppendInt64(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return msgp.AppendUint64(b, v.Uint()), nil
+	case reflect.Float32:
+		return msgp.AppendFloat32(b, float32(v.Float())), nil
+	case reflect.Float64:
+		return msgp.AppendFloat64(b, v.Float()), nil
+	case reflect.String:
+		return msgp.AppendString(b, v.String()), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return msgp.AppendNil(b), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return msgp.AppendBytes(b, v.Bytes()), nil
+		}
+		return appendMsgPackArray(b, v)
+	case reflect.Array:
+		return appendMsgPackArray(b, v)
+	case reflect.Map:
+		if v.IsNil() {
+			return msgp.AppendNil(b), nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return b, &msgp.ErrUnsupportedType{T: v.Type()}
+		}
+		keys := v.MapKeys()
+		// the keys are sorted to get a stable encoding
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		b = msgp.AppendMapHeader(b, uint32(len(keys)))
+		var err error
+		for _, key := range keys {
+			b = msgp.AppendString(b, key.String())
+			if b, err = appendMsgPack(b, v.MapIndex(key)); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		fields := structMsgPackFields(v.Type())
+		// Fields of nil embedded pointers are skipped
+		size := 0
+		for i := range fields {
+			if field, ok := msgPackFieldValue(v, fields[i].index); ok && (!fields[i].omitEmpty || !isEmptyValue(field)) {
+				size++
+			}
+		}
+		b = msgp.AppendMapHeader(b, uint32(size))
+		var err error
+		for i := range fields {
+			field, ok := msgPackFieldValue(v, fields[i].index)
+			if !ok || (fields[i].omitEmpty && isEmptyValue(field)) {
+				continue
+			}
+			b = msgp.AppendString(b, fields[i].name)
+			if b, err = appendMsgPack(b, field); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	}
+	return b, &msgp.ErrUnsupportedType{T: v.Type()}
+}
+
+func appendMsgPackArray(b []byte, v reflect.Value) ([]byte, error) {
+	b = msgp.AppendArrayHeader(b, uint32(v.Len()))
+	var err error
+	for i := 0; i < v.Len(); i++ {
+		if b, err = appendMsgPack(b, v.Index(i)); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+func decodeMsgPack(b []byte, v reflect.Value) (o []byte, err error) {
+	if msgp.IsNil(b) {
+		v.Set(reflect.Zero(v.Type()))
+		return msgp.ReadNilBytes(b)
+	}
+	if v.Type() == timeType {
+		var t time.Time
+		t, o, err = msgp.ReadTimeBytes(b)
+		v.Set(reflect.ValueOf(t))
+		return o, err
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(unmarshalerType) {
+		return v.Addr().Interface().(msgp.Unmarshaler).UnmarshalMsg(b)
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeMsgPack(b, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return b, &msgp.ErrUnsupportedType{T: v.Type()}
+		}
+		var i interface{}
+		if i, o, err = msgp.ReadIntfBytes(b); err == nil {
+			v.Set(reflect.ValueOf(i))
+		}
+		return o, err
+	case reflect.Bool:
+		var x bool
+		x, o, err = msgp.ReadBoolBytes(b)
+		v.SetBool(x)
+		return o, err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var x int64
+		if x, o, err = msgp.ReadInt64Bytes(b); err == nil && v.OverflowInt(x) {
+			return o, msgp.IntOverflow{Value: x, FailedBitsize: v.Type().Bits()}
+		}
+		v.SetInt(x)
+		return o, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var x uint64
+		if x, o, err = msgp.ReadUint64Bytes(b); err == nil && v.OverflowUint(x) {
+			return o, msgp.UintOverflow{Value: x, FailedBitsize: v.Type().Bits()}
+		}
+		v.SetUint(x)
+		return o, err
+	case reflect.Float32, reflect.Float64:
+		var x float64
+		x, o, err = msgp.ReadFloat64Bytes(b)
+		v.SetFloat(x)
+		return o, err
+	case reflect.String:
+		var x string
+		x, o, err = msgp.ReadStringBytes(b)
+		v.SetString(x)
+		return o, err
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			var x []byte
+			if x, o, err = msgp.ReadBytesBytes(b, nil); err == nil {
+				v.SetBytes(x)
+			}
+			return o, err
+		}
+		var size uint32
+		if size, b, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+			return b, err
+		}
+		// Every element takes at least one byte, the size is checked before the allocation
+		if size > uint32(len(b)) {
+			return b, msgp.ErrShortBytes
+		}
+		v.Set(reflect.MakeSlice(v.Type(), int(size), int(size)))
+		for i := 0; i < int(size); i++ {
+			if b, err = decodeMsgPack(b, v.Index(i)); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case reflect.Array:
+		var size uint32
+		if size, b, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+			return b, err
+		}
+		for i := 0; i < int(size); i++ {
+			if i < v.Len() {
+				b, err = decodeMsgPack(b, v.Index(i))
+			} else {
+				b, err = msgp.Skip(b)
+			}
+			if err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return b, &msgp.ErrUnsupportedType{T: v.Type()}
+		}
+		var size uint32
+		if size, b, err = msgp.ReadMapHeaderBytes(b); err != nil {
+			return b, err
+		}
+		if size > uint32(len(b)) {
+			return b, msgp.ErrShortBytes
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), int(size)))
+		}
+		for i := 0; i < int(size); i++ {
+			var key string
+			if key, b, err = msgp.ReadStringBytes(b); err != nil {
+				return b, err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if b, err = decodeMsgPack(b, value); err != nil {
+				return b, err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), value)
+		}
+		return b, nil
+	case reflect.Struct:
+		var size uint32
+		if size, b, err = msgp.ReadMapHeaderBytes(b); err != nil {
+			return b, err
+		}
+		fields := structMsgPackFields(v.Type())
+		for i := 0; i < int(size); i++ {
+			var key []byte
+			if key, b, err = msgp.ReadStringZC(b); err != nil {
+				return b, err
+			}
+			if field := findMsgPackField(fields, key); field != nil {
+				var value reflect.Value
+				if value, err = msgPackFieldAlloc(v, field.index); err != nil {
+					return b, err
+				}
+				b, err = decodeMsgPack(b, value)
+			} else {
+				b, err = msgp.Skip(b)
+			}
+			if err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	}
+	return b, &msgp.ErrUnsupportedType{T: v.Type()}
+}
+
+// findMsgPackField returns the field of the key, the exact name is preferred over a case-insensitive match
+func findMsgPackField(fields []msgPackField, key []byte) *msgPackField {
+	var fold *msgPackField
+	for i := range fields {
+		if fields[i].name == string(key) {
+			return &fields[i]
+		}
+		if fold == nil && strings.EqualFold(fields[i].name, string(key)) {
+			fold = &fields[i]
+		}
+	}
+	return fold
+}
+
+// structMsgPackFields returns the encoded fields of the struct type, embedded structs and pointers
+// to structs without a name are flattened like encoding/json does it
+func structMsgPackFields(t reflect.Type) []msgPackField {
+	if fields, ok := msgPackFields.Load(t); ok {
+		return fields.([]msgPackField)
+	}
+	fields := collectMsgPackFields(t, map[reflect.Type]bool{t: true})
+	msgPackFields.Store(t, fields)
+	return fields
+}
+
+// collectMsgPackFields collects the fields of the struct type, visited contains the embedded types
+// of the current path to stop at recursive embeddings
+func collectMsgPackFields(t reflect.Type, visited map[reflect.Type]bool) []msgPackField {
+	var fields []msgPackField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("msgpack")
+		if !ok {
+			tag = f.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if comma := strings.IndexByte(tag, ','); comma != -1 {
+			name, opts = tag[:comma], tag[comma+1:]
+		}
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if visited[embedded] {
+					continue
+				}
+				visited[embedded] = true
+				for _, field := range collectMsgPackFields(embedded, visited) {
+					field.index = append([]int{i}, field.index...)
+					fields = append(fields, field)
+				}
+				delete(visited, embedded)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, msgPackField{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	return fields
+}
+
+// msgPackFieldValue returns the field of the index, ok is false if an embedded pointer on the way is nil
+func msgPackFieldValue(v reflect.Value, index []int) (field reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// msgPackFieldAlloc returns the field of the index and allocates the nil embedded pointers on the way,
+// like encoding/json it fails for nil pointers to unexported structs
+func msgPackFieldAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, fmt.Errorf("msgpack: cannot set embedded pointer to unexported struct %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// isEmptyValue reports if the value is empty in the sense of the omitempty option of encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
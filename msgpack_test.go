@@ -0,0 +1,308 @@
+package lightning
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ikidev/lightning/internal/msgp"
+	"github.com/ikidev/lightning/utils"
+)
+
+type msgPackBase struct {
+	ID int64 `json:"id"`
+}
+
+// MsgPackAudit is exported, so the decoder can allocate it as an embedded pointer
+type MsgPackAudit struct {
+	CreatedBy string `json:"created_by"`
+}
+
+type msgPackPost struct {
+	*MsgPackAudit
+	*msgPackBase
+	Title string `json:"title"`
+}
+
+type msgPackUser struct {
+	msgPackBase
+	Name     string            `json:"name"`
+	Email    string            `msgpack:"mail" json:"email"`
+	Password string            `json:"-"`
+	Nick     string            `json:"nick,omitempty"`
+	Age      uint8             `json:"age"`
+	Score    float64           `json:"score"`
+	Admin    bool              `json:"admin"`
+	Roles    []string          `json:"roles"`
+	Labels   map[string]string `json:"labels"`
+	Avatar   []byte            `json:"avatar"`
+	Friend   *msgPackUser      `json:"friend"`
+	Created  time.Time         `json:"created"`
+	Extra    interface{}       `json:"extra"`
+	private  string
+}
+
+// go test -run Test_MsgPack_RoundTrip
+func Test_MsgPack_RoundTrip(t *testing.T) {
+	in := msgPackUser{
+		msgPackBase: msgPackBase{ID: 42},
+		Name:        "john",
+		Email:       "john@example.com",
+		Password:    "secret",
+		Age:         30,
+		Score:       1.5,
+		Admin:       true,
+		Roles:       []string{"admin", "dev"},
+		Labels:      map[string]string{"team": "core"},
+		Avatar:      []byte{1, 2, 3},
+		Friend:      &msgPackUser{Name: "doe"},
+		Created:     time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		Extra:       "value",
+		private:     "hidden",
+	}
+	raw, err := marshalMsgPack(&in)
+	utils.AssertEqual(t, nil, err)
+
+	// the keys of the map
+	decoded := map[string]interface{}{}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &decoded))
+	utils.AssertEqual(t, 12, len(decoded))
+	utils.AssertEqual(t, int64(42), decoded["id"])
+	utils.AssertEqual(t, "john@example.com", decoded["mail"])
+	_, ok := decoded["nick"]
+	utils.AssertEqual(t, false, ok)
+	_, ok = decoded["Password"]
+	utils.AssertEqual(t, false, ok)
+
+	out := msgPackUser{}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &out))
+	in.Password, in.private = "", ""
+	utils.AssertEqual(t, true, in.Created.Equal(out.Created))
+	utils.AssertEqual(t, true, out.Friend.Created.IsZero())
+	in.Created, in.Friend.Created = out.Created, out.Friend.Created
+	utils.AssertEqual(t, in, out)
+}
+
+// go test -run Test_MsgPack_EmbeddedPointer
+func Test_MsgPack_EmbeddedPointer(t *testing.T) {
+	in := msgPackPost{MsgPackAudit: &MsgPackAudit{CreatedBy: "john"}, msgPackBase: &msgPackBase{ID: 7}, Title: "hello"}
+	raw, err := marshalMsgPack(in)
+	utils.AssertEqual(t, nil, err)
+
+	// The fields are flattened like in JSON
+	decoded := map[string]interface{}{}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &decoded))
+	utils.AssertEqual(t, map[string]interface{}{"created_by": "john", "id": int64(7), "title": "hello"}, decoded)
+	jsonRaw, err := json.Marshal(in)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"created_by":"john","id":7,"title":"hello"}`, string(jsonRaw))
+
+	// Exported embedded pointers are allocated
+	out := msgPackPost{msgPackBase: &msgPackBase{}}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &out))
+	utils.AssertEqual(t, in, out)
+
+	// Unexported embedded pointers can't be allocated
+	err = unmarshalMsgPack(raw, &msgPackPost{})
+	utils.AssertEqual(t, "msgpack: cannot set embedded pointer to unexported struct lightning.msgPackBase", err.Error())
+
+	// The fields of nil pointers are left out
+	raw, err = marshalMsgPack(msgPackPost{Title: "empty"})
+	utils.AssertEqual(t, nil, err)
+	decoded = map[string]interface{}{}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &decoded))
+	utils.AssertEqual(t, map[string]interface{}{"title": "empty"}, decoded)
+}
+
+// go test -run Test_MsgPack_Decode
+func Test_MsgPack_Decode(t *testing.T) {
+	// case-insensitive keys and unknown keys
+	raw := msgp.AppendMapHeader(nil, 3)
+	raw = msgp.AppendString(raw, "NAME")
+	raw = msgp.AppendString(raw, "john")
+	raw = msgp.AppendString(raw, "unknown")
+	raw = msgp.AppendArrayHeader(raw, 1)
+	raw = msgp.AppendInt64(raw, 1)
+	raw = msgp.AppendString(raw, "age")
+	raw = msgp.AppendInt64(raw, 300)
+
+	out := msgPackUser{}
+	err := unmarshalMsgPack(raw, &out)
+	utils.AssertEqual(t, "john", out.Name)
+	utils.AssertEqual(t, true, err != nil)
+	_, ok := err.(msgp.UintOverflow)
+	utils.AssertEqual(t, true, ok)
+
+	utils.AssertEqual(t, "msgpack: decode target must be a non-nil pointer", unmarshalMsgPack(raw, out).Error())
+
+	var array [2]int
+	raw = msgp.AppendArrayHeader(nil, 3)
+	raw = msgp.AppendInt64(raw, 1)
+	raw = msgp.AppendInt64(raw, 2)
+	raw = msgp.AppendInt64(raw, 3)
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &array))
+	utils.AssertEqual(t, [2]int{1, 2}, array)
+
+	var ptr *string
+	utils.AssertEqual(t, nil, unmarshalMsgPack(msgp.AppendString(nil, "john"), &ptr))
+	utils.AssertEqual(t, "john", *ptr)
+	utils.AssertEqual(t, nil, unmarshalMsgPack(msgp.AppendNil(nil), &ptr))
+	utils.AssertEqual(t, true, ptr == nil)
+}
+
+// go test -run Test_MsgPack_Decode_Size
+func Test_MsgPack_Decode_Size(t *testing.T) {
+	// The sizes of the headers exceed the data, nothing is allocated
+	var slice []int
+	utils.AssertEqual(t, msgp.ErrShortBytes, unmarshalMsgPack(msgp.AppendArrayHeader(nil, 1<<31), &slice))
+	utils.AssertEqual(t, true, slice == nil)
+
+	var m map[string]int
+	utils.AssertEqual(t, msgp.ErrShortBytes, unmarshalMsgPack(msgp.AppendMapHeader(nil, 1<<31), &m))
+	utils.AssertEqual(t, true, m == nil)
+
+	// Valid data is still decoded
+	raw := msgp.AppendArrayHeader(nil, 2)
+	raw = msgp.AppendInt64(raw, 1)
+	raw = msgp.AppendInt64(raw, 2)
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &slice))
+	utils.AssertEqual(t, []int{1, 2}, slice)
+}
+
+// go test -run Test_MsgPack_Unsupported
+func Test_MsgPack_Unsupported(t *testing.T) {
+	_, err := marshalMsgPack(map[int]string{1: "one"})
+	utils.AssertEqual(t, true, err != nil)
+	_, err = marshalMsgPack(struct{ Fn func() }{})
+	utils.AssertEqual(t, true, err != nil)
+}
+
+// go test -v -run=^$ -bench=Benchmark_MsgPack_Marshal -benchmem -count=4
+func Benchmark_MsgPack_Marshal(b *testing.B) {
+	user := msgPackUser{Name: "john", Roles: []string{"admin"}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, _ = marshalMsgPack(&user)
+	}
+}
+
+// msgPackPoint implements the msgp interfaces like the generated code, it's encoded as an array
+type msgPackPoint struct {
+	X, Y int64
+}
+
+func (p *msgPackPoint) MarshalMsg(b []byte) ([]byte, error) {
+	b = msgp.AppendArrayHeader(b, 2)
+	b = msgp.AppendInt64(b, p.X)
+	return msgp.AppendInt64(b, p.Y), nil
+}
+
+func (p *msgPackPoint) UnmarshalMsg(b []byte) (o []byte, err error) {
+	var size uint32
+	if size, b, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+		return b, err
+	}
+	if size != 2 {
+		return b, msgp.ArrayError{Wanted: 2, Got: size}
+	}
+	if p.X, b, err = msgp.ReadInt64Bytes(b); err != nil {
+		return b, err
+	}
+	p.Y, b, err = msgp.ReadInt64Bytes(b)
+	return b, err
+}
+
+type msgPackShape struct {
+	Name   string         `json:"name"`
+	Points []msgPackPoint `json:"points"`
+}
+
+// go test -run Test_MsgPack_Marshaler
+func Test_MsgPack_Marshaler(t *testing.T) {
+	point := msgPackPoint{X: 1, Y: 2}
+	raw, err := marshalMsgPack(&point)
+	utils.AssertEqual(t, nil, err)
+	expected := msgp.AppendInt64(msgp.AppendInt64(msgp.AppendArrayHeader(nil, 2), 1), 2)
+	utils.AssertEqual(t, expected, raw)
+
+	out := msgPackPoint{}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &out))
+	utils.AssertEqual(t, point, out)
+
+	// the addressable elements use the marshaler of the pointer
+	shape := msgPackShape{Name: "line", Points: []msgPackPoint{{1, 2}, {3, 4}}}
+	raw, err = marshalMsgPack(shape)
+	utils.AssertEqual(t, nil, err)
+	decoded := map[string]interface{}{}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &decoded))
+	utils.AssertEqual(t, []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{int64(3), int64(4)}}, decoded["points"])
+
+	outShape := msgPackShape{}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(raw, &outShape))
+	utils.AssertEqual(t, shape, outShape)
+}
+
+// go test -run Test_Response_MsgPack
+func Test_Response_MsgPack(t *testing.T) {
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		return res.MsgPack(Map{"name": "john", "point": &msgPackPoint{X: 1, Y: 2}})
+	})
+	app.Get("/error", func(req *Request, res *Response) error {
+		return res.MsgPack(make(chan int))
+	})
+	app.Post("/", func(req *Request, res *Response) error {
+		shape := new(msgPackShape)
+		if err := req.ctx.BodyParser(shape); err != nil {
+			return err
+		}
+		return res.MsgPack(shape)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, MIMEApplicationMsgPack, resp.Header.Get(HeaderContentType))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	decoded := map[string]interface{}{}
+	utils.AssertEqual(t, nil, unmarshalMsgPack(body, &decoded))
+	utils.AssertEqual(t, Map{"name": "john", "point": []interface{}{int64(1), int64(2)}}, Map(decoded))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/error", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusInternalServerError, resp.StatusCode)
+
+	shape := msgPackShape{Name: "line", Points: []msgPackPoint{{1, 2}, {3, 4}}}
+	raw, err := marshalMsgPack(shape)
+	utils.AssertEqual(t, nil, err)
+	req := httptest.NewRequest(MethodPost, "/", bytes.NewReader(raw))
+	req.Header.Set(HeaderContentType, MIMEApplicationMsgPack)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, raw, body)
+
+	// the data doesn't match the struct
+	req = httptest.NewRequest(MethodPost, "/", bytes.NewReader(msgp.AppendString(nil, "line")))
+	req.Header.Set(HeaderContentType, MIMEApplicationMsgPack)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusInternalServerError, resp.StatusCode)
+}
+
+// go test -v -run=^$ -bench=Benchmark_MsgPack_Marshaler -benchmem -count=4
+func Benchmark_MsgPack_Marshaler(b *testing.B) {
+	point := &msgPackPoint{X: 1, Y: 2}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, _ = marshalMsgPack(point)
+	}
+}
@@ -0,0 +1,167 @@
+package lightning
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+)
+
+// FilePart is a file of a multipart body, it's read from the request body while it's received.
+// The part is only readable inside the callback of req.StreamFiles.
+type FilePart struct {
+	FieldName string               // Name of the form field
+	FileName  string               // Name of the file on the client
+	Header    textproto.MIMEHeader // MIME headers of the part
+
+	reader *partReader
+}
+
+// partReader enforces the size limit of a part
+type partReader struct {
+	part  *multipart.Part
+	limit int64
+	size  int64
+	files *[]*os.File
+	dir   string
+}
+
+// Read reads the next bytes of the part, it fails with ErrRequestEntityTooLarge if the part exceeds the limit
+func (r *partReader) Read(p []byte) (n int, err error) {
+	n, err = r.part.Read(p)
+	r.size += int64(n)
+	if r.limit > 0 && r.size > r.limit {
+		n -= int(r.size - r.limit)
+		r.size = r.limit
+		return n, ErrRequestEntityTooLarge
+	}
+	return n, err
+}
+
+// Read reads the content of the file
+func (p FilePart) Read(b []byte) (int, error) {
+	return p.reader.Read(b)
+}
+
+// ContentType returns the Content-Type of the part
+func (p FilePart) ContentType() string {
+	return p.Header.Get(HeaderContentType)
+}
+
+// Size returns the number of bytes which have been read from the part so far
+func (p FilePart) Size() int64 {
+	return p.reader.size
+}
+
+// Save writes the remaining content of the file to the path, the file is removed if the upload fails
+func (p FilePart) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, p.reader); err == nil {
+		err = file.Close()
+	} else {
+		_ = file.Close()
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+// Spill writes the remaining content of the file to a temporary file in Config.MultipartTempDir
+// and returns it positioned at the start. The file is closed and removed when req.StreamFiles returns,
+// use Save to keep the upload.
+func (p FilePart) Spill() (*os.File, error) {
+	file, err := ioutil.TempFile(p.reader.dir, "lightning-multipart-")
+	if err != nil {
+		return nil, err
+	}
+	*p.reader.files = append(*p.reader.files, file)
+	if _, err = io.Copy(file, p.reader); err != nil {
+		return nil, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// MultipartReader returns a reader for the parts of a multipart/form-data body.
+// The parts are read from the body stream if Config.StreamRequestBody is enabled,
+// otherwise from the buffered body. Set Config.DisablePreParseMultipartForm to read the
+// parts without a copy, a pre-parsed form is encoded again.
+func (req *Request) MultipartReader() (*multipart.Reader, error) {
+	boundary := req.ctx.fasthttp.Request.Header.MultipartFormBoundary()
+	if len(boundary) == 0 {
+		return nil, ErrUnsupportedMediaType
+	}
+	var body io.Reader
+	if stream := req.ctx.fasthttp.RequestBodyStream(); stream != nil {
+		body = stream
+	} else {
+		body = bytes.NewReader(req.ctx.fasthttp.Request.Body())
+	}
+	return multipart.NewReader(body, string(boundary)), nil
+}
+
+// StreamFiles reads the multipart/form-data body part by part and calls fn for every file.
+// The files aren't buffered, fn reads them from the body while they're received.
+// Form fields are added to the post args, so req.FormValue returns the fields which preceded the file.
+// Parts bigger than Config.MultipartPartLimit fail with ErrRequestEntityTooLarge.
+//  err := req.StreamFiles(func(part lightning.FilePart) error {
+//  	return part.Save("./uploads/" + filepath.Base(part.FileName))
+//  })
+func (req *Request) StreamFiles(fn func(part FilePart) error) error {
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	cfg := req.ctx.app.config
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		reader := &partReader{part: part, limit: cfg.MultipartPartLimit, files: &files, dir: cfg.MultipartTempDir}
+		if part.FileName() == "" {
+			// Form fields are kept in memory
+			if cfg.BodyLimit > 0 && (reader.limit <= 0 || reader.limit > int64(cfg.BodyLimit)) {
+				reader.limit = int64(cfg.BodyLimit)
+			}
+			value, err := ioutil.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			req.ctx.fasthttp.PostArgs().AddBytesV(part.FormName(), value)
+			continue
+		}
+		err = fn(FilePart{
+			FieldName: part.FormName(),
+			FileName:  part.FileName(),
+			Header:    part.Header,
+			reader:    reader,
+		})
+		if err != nil {
+			return err
+		}
+		// The rest of the part is discarded, but it's still limited
+		if _, err = io.Copy(ioutil.Discard, reader); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package lightning
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// multipartRequest creates a multipart/form-data request with a title field and a file of the size
+func multipartRequest(t *testing.T, target string, size int) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	utils.AssertEqual(t, nil, w.WriteField("title", "holiday"))
+	file, err := w.CreateFormFile("video", "holiday.mp4")
+	utils.AssertEqual(t, nil, err)
+	_, err = file.Write(bytes.Repeat([]byte("a"), size))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, w.Close())
+
+	req := httptest.NewRequest(MethodPost, target, &body)
+	req.Header.Set(HeaderContentType, w.FormDataContentType())
+	return req
+}
+
+// go test -run Test_Request_StreamFiles
+func Test_Request_StreamFiles(t *testing.T) {
+	handler := func(req *Request, res *Response) error {
+		var result []string
+		err := req.StreamFiles(func(part FilePart) error {
+			n, err := io.Copy(ioutil.Discard, part)
+			if err != nil {
+				return err
+			}
+			result = append(result, req.FormValue("title"), part.FieldName, part.FileName, part.ContentType(), strconv.FormatInt(n, 10), strconv.FormatInt(part.Size(), 10))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return res.String(strings.Join(result, ","))
+	}
+
+	configs := map[string]Config{
+		"buffered":  {},
+		"preparsed": {StreamRequestBody: true, BodyLimit: 1024},
+		"streamed":  {StreamRequestBody: true, BodyLimit: 1024, DisablePreParseMultipartForm: true},
+	}
+	for name, config := range configs {
+		app := New(config)
+		app.Post("/", handler)
+
+		resp, err := app.Test(multipartRequest(t, "/", 64*1024))
+		utils.AssertEqual(t, nil, err, name)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, name)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, name)
+		utils.AssertEqual(t, "holiday,video,holiday.mp4,application/octet-stream,65536,65536", string(body), name)
+	}
+}
+
+// go test -run Test_Request_StreamFiles_Limit
+func Test_Request_StreamFiles_Limit(t *testing.T) {
+	app := New(Config{StreamRequestBody: true, DisablePreParseMultipartForm: true, MultipartPartLimit: 1024})
+	app.Post("/", func(req *Request, res *Response) error {
+		return req.StreamFiles(func(part FilePart) error {
+			return nil
+		})
+	})
+
+	resp, err := app.Test(multipartRequest(t, "/", 1024))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	// the rest of the part is also limited if it's not read by the callback
+	resp, err = app.Test(multipartRequest(t, "/", 1025))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+// go test -run Test_Request_StreamFiles_Save
+func Test_Request_StreamFiles_Save(t *testing.T) {
+	dir := t.TempDir()
+	spillDir := t.TempDir()
+	app := New(Config{StreamRequestBody: true, DisablePreParseMultipartForm: true, MultipartTempDir: spillDir})
+	app.Post("/save", func(req *Request, res *Response) error {
+		return req.StreamFiles(func(part FilePart) error {
+			return part.Save(filepath.Join(dir, part.FileName))
+		})
+	})
+	app.Post("/spill", func(req *Request, res *Response) error {
+		var spilled string
+		err := req.StreamFiles(func(part FilePart) error {
+			file, err := part.Spill()
+			if err != nil {
+				return err
+			}
+			utils.AssertEqual(t, spillDir, filepath.Dir(file.Name()))
+			content, err := ioutil.ReadAll(file)
+			spilled = string(content)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return res.String(spilled)
+	})
+
+	resp, err := app.Test(multipartRequest(t, "/save", 10))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "holiday.mp4"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "aaaaaaaaaa", string(content))
+
+	resp, err = app.Test(multipartRequest(t, "/spill", 10))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "aaaaaaaaaa", string(body))
+
+	// the spilled files are removed after the body has been read
+	entries, err := os.ReadDir(spillDir)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 0, len(entries))
+}
+
+// go test -run Test_Request_MultipartReader
+func Test_Request_MultipartReader(t *testing.T) {
+	app := New(Config{DisablePreParseMultipartForm: true})
+	app.Post("/", func(req *Request, res *Response) error {
+		mr, err := req.MultipartReader()
+		if err != nil {
+			return err
+		}
+		part, err := mr.NextPart()
+		if err != nil {
+			return err
+		}
+		value, err := ioutil.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		return res.String(part.FormName() + "=" + string(value))
+	})
+
+	resp, err := app.Test(multipartRequest(t, "/", 10))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "title=holiday", string(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/", strings.NewReader("title=holiday")))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusUnsupportedMediaType, resp.StatusCode)
+}
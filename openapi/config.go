@@ -0,0 +1,60 @@
+package openapi
+
+import (
+	"github.com/ikidev/lightning"
+)
+
+// Config defines the config for the OpenAPI document.
+type Config struct {
+	// Title of the API
+	//
+	// Optional. Default: "Lightning API"
+	Title string
+
+	// Version of the API document
+	//
+	// Optional. Default: "1.0.0"
+	Version string
+
+	// Description of the API
+	//
+	// Optional. Default: ""
+	Description string
+
+	// Servers are the base URLs of the API, e.g. "https://api.example.com"
+	//
+	// Optional. Default: nil
+	Servers []string
+
+	// Filter defines a function to skip a route when returned false,
+	// e.g. to document a single API version or to hide internal routes.
+	//
+	// Optional. Default: nil
+	Filter func(route *lightning.Route) bool
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Title:   "Lightning API",
+	Version: "1.0.0",
+}
+
+// Helper function to set default values
+func configDefault(config ...Config) Config {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Title == "" {
+		cfg.Title = ConfigDefault.Title
+	}
+	if cfg.Version == "" {
+		cfg.Version = ConfigDefault.Version
+	}
+	return cfg
+}
@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ikidev/lightning"
+	"github.com/ikidev/lightning/utils"
+)
+
+// Version of the OpenAPI specification of the documents
+const Version = "3.0.3"
+
+// New creates the OpenAPI document of the routes in app.Stack(). The paths are converted from ":id" to "{id}",
+// routes with optional groups like "/docs(/:lang)?" are documented with a path for every variant,
+// the metadata of the routes like Summary, Tags, RequestType and ResponseType is used for the operations.
+// Middlewares are skipped and HEAD operations are left out if the path has a GET operation.
+func New(app *lightning.App, config ...Config) *Document {
+	cfg := configDefault(config...)
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       cfg.Title,
+			Description: cfg.Description,
+			Version:     cfg.Version,
+		},
+		Paths: make(map[string]PathItem),
+	}
+	for _, server := range cfg.Servers {
+		doc.Servers = append(doc.Servers, Server{URL: server})
+	}
+
+	g := &generator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+	for _, routes := range app.Stack() {
+		for _, route := range routes {
+			if route.IsMiddleware() || (cfg.Filter != nil && !cfg.Filter(route)) {
+				continue
+			}
+			// Every variant of the optional groups is a path of its own
+			for _, segs := range route.PathVariants() {
+				path, params := convertPath(segs)
+				item, ok := doc.Paths[path]
+				if !ok {
+					item = make(PathItem)
+					doc.Paths[path] = item
+				}
+				method := utils.ToLower(route.Method)
+				// The first route wins, e.g. for the same path in different versions
+				if _, ok = item[method]; ok {
+					continue
+				}
+				item[method] = g.operation(route, params)
+			}
+		}
+	}
+	// HEAD operations of Get routes
+	for _, item := range doc.Paths {
+		if _, ok := item["get"]; ok {
+			delete(item, "head")
+		}
+	}
+
+	if len(g.schemas) > 0 {
+		doc.Components = &Components{Schemas: g.schemas}
+	}
+	return doc
+}
+
+// Handler serves the OpenAPI document of the app as JSON, the document is created
+// for every request to include the routes which are added at runtime.
+//  app.Get("/openapi.json", openapi.Handler(app, openapi.Config{Title: "Users"}))
+func Handler(app *lightning.App, config ...Config) lightning.Handler {
+	return func(req *lightning.Request, res *lightning.Response) error {
+		return res.JSON(New(app, config...))
+	}
+}
+
+// operation creates the operation of a route
+func (g *generator) operation(route *lightning.Route, params []*Parameter) *Operation {
+	op := &Operation{
+		OperationID: route.Name,
+		Summary:     route.Meta.Summary,
+		Tags:        route.Meta.Tags,
+		Parameters:  params,
+		Responses:   make(map[string]Response),
+	}
+
+	if t := route.Meta.Request; t != nil {
+		g.request(op, t, route.Method)
+	}
+
+	for status, t := range route.Meta.Responses {
+		response := Response{Description: utils.StatusMessage(status)}
+		if t != nil {
+			response.Content = map[string]MediaType{
+				lightning.MIMEApplicationJSON: {Schema: g.schema(t)},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+	}
+	// An operation needs at least one response
+	if len(op.Responses) == 0 {
+		op.Responses[strconv.Itoa(lightning.StatusOK)] = Response{Description: utils.StatusMessage(lightning.StatusOK)}
+	}
+	return op
+}
+
+// request adds the parameters and the body of the request type to the operation
+func (g *generator) request(op *Operation, t reflect.Type, method string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	hasBody := method != lightning.MethodGet && method != lightning.MethodHead
+	if t.Kind() != reflect.Struct {
+		if hasBody {
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				lightning.MIMEApplicationJSON: {Schema: g.schema(t)},
+			}}
+		}
+		return
+	}
+
+	body := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	isParams := false
+	visitFields(t, func(field reflect.StructField) {
+		if name := tagName(field, "query"); name != "" {
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "query", Schema: g.schema(field.Type)})
+			isParams = true
+		} else if name = tagName(field, "reqHeader"); name != "" {
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "header", Schema: g.schema(field.Type)})
+			isParams = true
+		} else if name = tagName(field, "cookie"); name != "" {
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "cookie", Schema: g.schema(field.Type)})
+			isParams = true
+		} else if name = tagName(field, "param"); name != "" {
+			// The type of the field describes the path param
+			for _, param := range op.Parameters {
+				if param.In == "path" && param.Name == name {
+					param.Schema = g.schema(field.Type)
+				}
+			}
+			isParams = true
+		} else if name, required := jsonName(field); name != "" {
+			body.Properties[name] = g.schema(field.Type)
+			if required {
+				body.Required = append(body.Required, name)
+			}
+		}
+	})
+	if !hasBody || len(body.Properties) == 0 {
+		return
+	}
+	// A request type without parameters is referenced as a whole
+	if !isParams {
+		body = g.schema(t)
+	}
+	op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+		lightning.MIMEApplicationJSON: {Schema: body},
+	}}
+}
+
+// convertPath converts a variant of the route path to an OpenAPI path and returns its path parameters,
+// e.g. "/users/:id<int>/files/*" to "/users/{id}/files/{*1}"
+func convertPath(segs []lightning.PathSegment) (string, []*Parameter) {
+	var sb strings.Builder
+	var params []*Parameter
+	for _, seg := range segs {
+		if seg.Param == "" {
+			sb.WriteString(seg.Const)
+			continue
+		}
+		sb.WriteString("{" + seg.Param + "}")
+		params = append(params, &Parameter{Name: seg.Param, In: "path", Required: true, Schema: constraintSchema(seg.Constraints)})
+	}
+	return sb.String(), params
+}
+
+// constraintSchema returns the schema of the first type constraint of a path parameter
+func constraintSchema(constraints []string) *Schema {
+	for _, constraint := range constraints {
+		switch constraint {
+		case "int":
+			return &Schema{Type: "integer"}
+		case "bool":
+			return &Schema{Type: "boolean"}
+		case "float":
+			return &Schema{Type: "number"}
+		case "guid":
+			return &Schema{Type: "string", Format: "uuid"}
+		}
+	}
+	return &Schema{Type: "string"}
+}
@@ -0,0 +1,258 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ikidev/lightning"
+	"github.com/ikidev/lightning/utils"
+)
+
+type user struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Email   *string   `json:"email"`
+	Tags    []string  `json:"tags,omitempty"`
+	Friends []*user   `json:"friends,omitempty"`
+	Created time.Time `json:"created"`
+	secret  string
+}
+
+type createUser struct {
+	DryRun  bool                   `query:"dry_run"`
+	TraceID string                 `reqHeader:"X-Trace-Id"`
+	Session string                 `cookie:"session"`
+	Name    string                 `json:"name"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+type listUsers struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
+
+// go test -run Test_OpenAPI
+func Test_OpenAPI(t *testing.T) {
+	t.Parallel()
+	app := lightning.New()
+	handler := func(req *lightning.Request, res *lightning.Response) error { return nil }
+
+	app.Use(handler)
+	app.Get("/users", handler).Name("users.list").Summary("List users").Tags("users").
+		RequestType(listUsers{}).ResponseType(lightning.StatusOK, []user{})
+	app.Post("/users", handler).Tags("users").RequestType(&createUser{}).
+		ResponseType(lightning.StatusCreated, user{}).ResponseType(lightning.StatusBadRequest, nil)
+	api := app.Group("/api")
+	api.Put("/users/:id<int>/files/*", handler).RequestType([]byte{})
+	api.Delete("/users/:uuid<guid>?", handler).Summary("Delete a user")
+	api.Patch("/users/:id", handler).RequestType(struct {
+		ID uint16 `param:"id"`
+	}{})
+
+	doc := New(app, Config{Title: "Users", Servers: []string{"https://api.example.com"}})
+	utils.AssertEqual(t, Version, doc.OpenAPI)
+	utils.AssertEqual(t, Info{Title: "Users", Version: "1.0.0"}, doc.Info)
+	utils.AssertEqual(t, []Server{{URL: "https://api.example.com"}}, doc.Servers)
+	utils.AssertEqual(t, 4, len(doc.Paths))
+
+	// GET /users
+	list := doc.Paths["/users"]["get"]
+	utils.AssertEqual(t, 2, len(doc.Paths["/users"])) // GET and POST without HEAD
+	utils.AssertEqual(t, "users.list", list.OperationID)
+	utils.AssertEqual(t, "List users", list.Summary)
+	utils.AssertEqual(t, []string{"users"}, list.Tags)
+	utils.AssertEqual(t, []*Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int64"}},
+		{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Format: "int64"}},
+	}, list.Parameters)
+	utils.AssertEqual(t, true, list.RequestBody == nil)
+	utils.AssertEqual(t, &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/user"}},
+		list.Responses["200"].Content[lightning.MIMEApplicationJSON].Schema)
+
+	// POST /users
+	create := doc.Paths["/users"]["post"]
+	utils.AssertEqual(t, []*Parameter{
+		{Name: "dry_run", In: "query", Schema: &Schema{Type: "boolean"}},
+		{Name: "X-Trace-Id", In: "header", Schema: &Schema{Type: "string"}},
+		{Name: "session", In: "cookie", Schema: &Schema{Type: "string"}},
+	}, create.Parameters)
+	utils.AssertEqual(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name": {Type: "string"},
+			"meta": {Type: "object", AdditionalProperties: &Schema{}},
+		},
+		Required: []string{"name"},
+	}, create.RequestBody.Content[lightning.MIMEApplicationJSON].Schema)
+	utils.AssertEqual(t, "Created", create.Responses["201"].Description)
+	utils.AssertEqual(t, Response{Description: "Bad Request"}, create.Responses["400"])
+
+	// PUT /api/users/{id}/files/{*1}
+	put := doc.Paths["/api/users/{id}/files/{*1}"]["put"]
+	utils.AssertEqual(t, []*Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer"}},
+		{Name: "*1", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+	}, put.Parameters)
+	utils.AssertEqual(t, &Schema{Type: "string", Format: "byte"}, put.RequestBody.Content[lightning.MIMEApplicationJSON].Schema)
+	utils.AssertEqual(t, Response{Description: "OK"}, put.Responses["200"])
+
+	// DELETE /api/users/{uuid}
+	del := doc.Paths["/api/users/{uuid}"]["delete"]
+	utils.AssertEqual(t, "Delete a user", del.Summary)
+	utils.AssertEqual(t, &Schema{Type: "string", Format: "uuid"}, del.Parameters[0].Schema)
+
+	// PATCH /api/users/{id}
+	patch := doc.Paths["/api/users/{id}"]["patch"]
+	utils.AssertEqual(t, []*Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int32"}},
+	}, patch.Parameters)
+	utils.AssertEqual(t, true, patch.RequestBody == nil)
+
+	// Named structs are components
+	utils.AssertEqual(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"id":      {Type: "integer", Format: "int64"},
+			"name":    {Type: "string"},
+			"email":   {Type: "string"},
+			"tags":    {Type: "array", Items: &Schema{Type: "string"}},
+			"friends": {Type: "array", Items: &Schema{Ref: "#/components/schemas/user"}},
+			"created": {Type: "string", Format: "date-time"},
+		},
+		Required: []string{"id", "name", "created"},
+	}, doc.Components.Schemas["user"])
+}
+
+// go test -run Test_OpenAPI_Filter
+func Test_OpenAPI_Filter(t *testing.T) {
+	t.Parallel()
+	app := lightning.New()
+	handler := func(req *lightning.Request, res *lightning.Response) error { return nil }
+
+	app.Version("1").Add(lightning.MethodGet, "/users", handler).Summary("v1")
+	app.Version("2").Add(lightning.MethodGet, "/users", handler).Summary("v2")
+	app.Head("/status", handler)
+
+	doc := New(app, Config{Filter: func(route *lightning.Route) bool {
+		return route.Version != "1"
+	}})
+	utils.AssertEqual(t, "v2", doc.Paths["/users"]["get"].Summary)
+	utils.AssertEqual(t, true, doc.Paths["/status"]["head"] != nil)
+	utils.AssertEqual(t, true, doc.Components == nil)
+}
+
+// go test -run Test_OpenAPI_Handler
+func Test_OpenAPI_Handler(t *testing.T) {
+	t.Parallel()
+	app := lightning.New()
+	app.Get("/openapi.json", Handler(app)).Summary("OpenAPI document")
+
+	resp, err := app.Test(httptest.NewRequest(lightning.MethodGet, "/openapi.json", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, lightning.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, lightning.MIMEApplicationJSON, resp.Header.Get(lightning.HeaderContentType))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+
+	var doc Document
+	utils.AssertEqual(t, nil, json.Unmarshal(body, &doc))
+	utils.AssertEqual(t, "Lightning API", doc.Info.Title)
+	utils.AssertEqual(t, "OpenAPI document", doc.Paths["/openapi.json"]["get"].Summary)
+}
+
+// go test -race -run Test_OpenAPI_Handler_RuntimeMeta
+func Test_OpenAPI_Handler_RuntimeMeta(t *testing.T) {
+	t.Parallel()
+	app := lightning.New()
+	app.Get("/openapi.json", Handler(app))
+	handler := func(req *lightning.Request, res *lightning.Response) error { return nil }
+
+	// The metadata of routes which are added at runtime is set while the document is served
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := app.Test(httptest.NewRequest(lightning.MethodGet, "/openapi.json", nil))
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, lightning.StatusOK, resp.StatusCode)
+		}()
+		path := "/items/" + strconv.Itoa(i)
+		app.Get(path, handler).Summary("Item "+strconv.Itoa(i)).Tags("items").
+			ResponseType(lightning.StatusOK, user{})
+	}
+	wg.Wait()
+
+	doc := New(app)
+	utils.AssertEqual(t, "Item 9", doc.Paths["/items/9"]["get"].Summary)
+	utils.AssertEqual(t, []string{"items"}, doc.Paths["/items/9"]["get"].Tags)
+	utils.AssertEqual(t, true, doc.Paths["/items/9"]["get"].Responses["200"].Content != nil)
+}
+
+// go test -run Test_ConvertPath
+func Test_ConvertPath(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		path    string
+		results []string
+		params  [][]string
+	}{
+		{path: "/", results: []string{"/"}, params: [][]string{nil}},
+		{path: "/users/:id", results: []string{"/users/{id}"}, params: [][]string{{"id"}}},
+		{path: "/users/:id?", results: []string{"/users/{id}"}, params: [][]string{{"id"}}},
+		{path: "/flights/:from-:to", results: []string{"/flights/{from}-{to}"}, params: [][]string{{"from", "to"}}},
+		{path: "/files/:name.:ext", results: []string{"/files/{name}.{ext}"}, params: [][]string{{"name", "ext"}}},
+		{path: "/shop/:id<int;min(1)>/*", results: []string{"/shop/{id}/{*1}"}, params: [][]string{{"id", "*1"}}},
+		{path: "/tags/:tag<regex(^[a-z>]+$)>/posts", results: []string{"/tags/{tag}/posts"}, params: [][]string{{"tag"}}},
+		{path: "/+/+", results: []string{"/{+1}/{+2}"}, params: [][]string{{"+1", "+2"}}},
+		{path: "/api(/v:version)?/users", results: []string{"/api/v{version}/users", "/api/users"}, params: [][]string{{"version"}, nil}},
+		{path: `/v1/some/resource/name\:customVerb`, results: []string{"/v1/some/resource/name:customVerb"}, params: [][]string{nil}},
+	}
+	handler := func(req *lightning.Request, res *lightning.Response) error { return nil }
+	for _, tc := range testCases {
+		app := lightning.New()
+		app.Get(tc.path, handler).Name("route")
+		route := app.GetRoute("route")
+		var results []string
+		var params [][]string
+		for _, segs := range route.PathVariants() {
+			result, variantParams := convertPath(segs)
+			results = append(results, result)
+			var names []string
+			for _, param := range variantParams {
+				names = append(names, param.Name)
+			}
+			params = append(params, names)
+		}
+		utils.AssertEqual(t, tc.results, results, tc.path)
+		utils.AssertEqual(t, tc.params, params, tc.path)
+	}
+}
+
+// go test -run Test_OpenAPI_PathVariants
+func Test_OpenAPI_PathVariants(t *testing.T) {
+	t.Parallel()
+	app := lightning.New()
+	handler := func(req *lightning.Request, res *lightning.Response) error { return nil }
+	app.Get("/docs(/:lang<ALPHA>)?/pages/:page<INT>", handler)
+	app.Delete("/tags/:tag<regex(^[a-z>]+$)>", handler)
+
+	doc := New(app)
+	utils.AssertEqual(t, 3, len(doc.Paths))
+	// The params of the optional group are only part of its own variant
+	utils.AssertEqual(t, []*Parameter{
+		{Name: "lang", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "page", In: "path", Required: true, Schema: &Schema{Type: "integer"}},
+	}, doc.Paths["/docs/{lang}/pages/{page}"]["get"].Parameters)
+	utils.AssertEqual(t, []*Parameter{
+		{Name: "page", In: "path", Required: true, Schema: &Schema{Type: "integer"}},
+	}, doc.Paths["/docs/pages/{page}"]["get"].Parameters)
+	utils.AssertEqual(t, []*Parameter{
+		{Name: "tag", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+	}, doc.Paths["/tags/{tag}"]["delete"].Parameters)
+}
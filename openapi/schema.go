@@ -0,0 +1,136 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// generator creates the schemas of the types, named structs are stored in the components
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// schema returns the schema of the type
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.schemaName(t)
+			g.names[t] = name
+			// Reserve the name before the fields, the struct can reference itself
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// Interfaces and unsupported types allow any value
+	return &Schema{}
+}
+
+// structSchema returns the schema of the json fields of the struct
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	visitFields(t, func(field reflect.StructField) {
+		name, required := jsonName(field)
+		if name == "" {
+			return
+		}
+		schema.Properties[name] = g.schema(field.Type)
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	})
+	return schema
+}
+
+// schemaName returns the name of the struct in the components, it's qualified by the package on a collision
+func (g *generator) schemaName(t reflect.Type) string {
+	name := t.Name()
+	if _, ok := g.schemas[name]; ok {
+		name = strings.ReplaceAll(t.PkgPath(), "/", "_") + "." + name
+	}
+	return name
+}
+
+// visitFields calls fn for the exported fields of the struct, embedded structs without a name are flattened
+func visitFields(t reflect.Type, fn func(field reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				visitFields(ft, fn)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		fn(field)
+	}
+}
+
+// jsonName returns the json name of the field, fields are required if they are neither pointers nor omitempty
+func jsonName(field reflect.StructField) (name string, required bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, opts := tag, ""
+	if i := strings.IndexByte(tag, ','); i != -1 {
+		name, opts = tag[:i], tag[i+1:]
+	}
+	if name == "" {
+		name = field.Name
+	}
+	required = field.Type.Kind() != reflect.Ptr && !strings.Contains(opts, "omitempty")
+	return name, required
+}
+
+// tagName returns the name of the field in the given tag, e.g. `query:"page"`
+func tagName(field reflect.StructField, key string) string {
+	tag := field.Tag.Get(key)
+	if i := strings.IndexByte(tag, ','); i != -1 {
+		tag = tag[:i]
+	}
+	if tag == "-" {
+		return ""
+	}
+	return tag
+}
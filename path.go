@@ -7,8 +7,15 @@
 package lightning
 
 import (
+	"fmt"
+	"math/bits"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/ikidev/lightning/utils"
 )
@@ -19,6 +26,13 @@ type routeParser struct {
 	params        []string        // that parameter names the parsed route
 	wildCardCount int             // number of wildcard parameters, used internally to give the wildcard parameter its number
 	plusCount     int             // number of plus parameters, used internally to give the plus parameter its number
+	variants      []routeVariant  // expanded variants of the optional groups, ordered from the most to the least specific
+}
+
+// routeVariant is one possible form of a route with optional groups, e.g. "/docs/intro" for "/docs(/:lang)?/intro"
+type routeVariant struct {
+	parser         routeParser // parser for the segments of the variant
+	paramPositions []int       // positions of the variant parameters in the parameters of the whole route
 }
 
 // paramsSeg holds the segment metadata
@@ -36,7 +50,8 @@ type routeSegment struct {
 	IsLast           bool // shows if the segment is the last one for the route
 	HasOptionalSlash bool // segment has the possibility of an optional slash
 	Length           int  // length of the parameter for segment, when its 0 then the length is undetermined
-	// future TODO: add support for optional groups "/abc(/def)?"
+	// constraint information
+	Constraints []*paramConstraint // constraints which the parameter value must fulfill, e.g. ":id<int;min(1)>"
 }
 
 // different special routing signs
@@ -47,6 +62,16 @@ const (
 	paramStarterChar byte = ':'  // start character for a parameter with name
 	slashDelimiter   byte = '/'  // separator for the route, unlike the other delimiters this character at the end can be optional
 	escapeChar       byte = '\\' // escape character
+	// constraint signs
+	paramConstraintStart     byte = '<' // starts the constraint part of a parameter
+	paramConstraintEnd       byte = '>' // ends the constraint part of a parameter
+	paramConstraintDataStart byte = '(' // starts the data of a single constraint
+	paramConstraintDataEnd   byte = ')' // ends the data of a single constraint
+	paramConstraintSeparator byte = ';' // separates multiple constraints of a parameter
+	paramConstraintDataSep   byte = ',' // separates multiple values in the data of a constraint
+	// optional group signs, e.g. "/abc(/def)?"
+	groupStart byte = '('
+	groupEnd   byte = ')'
 )
 
 // list of possible parameter and segment delimiter
@@ -58,24 +83,42 @@ var (
 	// list of chars of delimiters and the starting parameter name char
 	parameterDelimiterChars = append([]byte{paramStarterChar}, routeDelimiter...)
 	// list of chars to find the end of a parameter
-	parameterEndChars = append([]byte{optionalParam}, parameterDelimiterChars...)
+	parameterEndChars = append([]byte{optionalParam, paramConstraintStart, groupStart, groupEnd}, parameterDelimiterChars...)
 )
 
 // parseRoute analyzes the route and divides it into segments for constant areas and parameters,
 // this information is needed later when assigning the requests to the declared routes
 func parseRoute(pattern string) routeParser {
 	parser := routeParser{}
+	// optional groups and the groups of each segment, only filled if the route has optional groups
+	groups := findOptionalGroups(pattern)
+	var openGroups []int
+	var segGroups [][]int
 
 	part := ""
+	offset := 0
 	for len(pattern) > 0 {
-		nextParamPosition := findNextParamPosition(pattern)
-		// handle the parameter part
-		if nextParamPosition == 0 {
-			processedPart, seg := parser.analyseParameterPart(pattern)
-			parser.params, parser.segs, part = append(parser.params, seg.ParamName), append(parser.segs, seg), processedPart
+		if groups.isStart(offset) {
+			// open a new optional group
+			openGroups = append(openGroups[:len(openGroups):len(openGroups)], groups.open(openGroups))
+			part = pattern[:1]
+		} else if groups.isEnd(offset) {
+			// close the current optional group, the optional sign is processed as well
+			openGroups = openGroups[:len(openGroups)-1]
+			part = pattern[:2]
 		} else {
-			processedPart, seg := parser.analyseConstantPart(pattern, nextParamPosition)
-			parser.segs, part = append(parser.segs, seg), processedPart
+			nextParamPosition := findNextParamPosition(pattern)
+			// handle the parameter part
+			if nextParamPosition == 0 {
+				processedPart, seg := parser.analyseParameterPart(pattern)
+				parser.params, parser.segs, part = append(parser.params, seg.ParamName), append(parser.segs, seg), processedPart
+			} else {
+				// the constant part ends in front of the next optional group sign
+				nextParamPosition = groups.nextPosition(offset, nextParamPosition, len(pattern))
+				processedPart, seg := parser.analyseConstantPart(pattern, nextParamPosition)
+				parser.segs, part = append(parser.segs, seg), processedPart
+			}
+			segGroups = append(segGroups, openGroups)
 		}
 
 		// reduce the pattern by the processed parts
@@ -83,6 +126,11 @@ func parseRoute(pattern string) routeParser {
 			break
 		}
 		pattern = pattern[len(part):]
+		offset += len(part)
+	}
+	// expand the optional groups before the segments are modified with the meta information
+	if len(groups.parents) > 0 {
+		parser.variants = buildRouteVariants(parser.segs, segGroups, groups.parents)
 	}
 	// mark last segment
 	if len(parser.segs) > 0 {
@@ -156,6 +204,160 @@ func findNextParamPosition(pattern string) int {
 	return nextParamPosition
 }
 
+// maxOptionalGroups defines the maximum number of optional groups per route, every group doubles the number of variants
+const maxOptionalGroups = 8
+
+// optionalGroups holds the positions of the optional groups "(...)?" in the route pattern
+type optionalGroups struct {
+	starts  map[int]bool // positions of the group start signs
+	ends    map[int]bool // positions of the group end signs
+	parents []int        // enclosing group of every opened group, -1 for groups on the top level
+}
+
+// findOptionalGroups search the optional groups in the pattern, brackets without a following optional sign
+// and brackets in the constraint part of a parameter are treated as normal characters
+func findOptionalGroups(pattern string) optionalGroups {
+	groups := optionalGroups{}
+	if strings.IndexByte(pattern, groupStart) == -1 {
+		return groups
+	}
+	var openStarts []int
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case escapeChar:
+			// skip the escaped character
+			i++
+		case paramConstraintStart:
+			if end := findConstraintEnd(pattern[i:]); end != -1 {
+				i += end
+			}
+		case groupStart:
+			openStarts = append(openStarts, i)
+		case groupEnd:
+			if len(openStarts) == 0 {
+				continue
+			}
+			start := openStarts[len(openStarts)-1]
+			openStarts = openStarts[:len(openStarts)-1]
+			if len(pattern) > i+1 && pattern[i+1] == optionalParam {
+				if groups.starts == nil {
+					groups.starts, groups.ends = make(map[int]bool), make(map[int]bool)
+				}
+				groups.starts[start], groups.ends[i] = true, true
+			}
+		}
+	}
+	return groups
+}
+
+// isStart checks if an optional group starts at the position
+func (groups *optionalGroups) isStart(pos int) bool {
+	return groups.starts[pos]
+}
+
+// isEnd checks if an optional group ends at the position
+func (groups *optionalGroups) isEnd(pos int) bool {
+	return groups.ends[pos]
+}
+
+// open registers a new group inside the currently open groups and returns its identifier
+func (groups *optionalGroups) open(openGroups []int) int {
+	parent := -1
+	if len(openGroups) > 0 {
+		parent = openGroups[len(openGroups)-1]
+	}
+	groups.parents = append(groups.parents, parent)
+	if len(groups.parents) > maxOptionalGroups {
+		panic(fmt.Sprintf("route: too many optional groups, the maximum is %d\n", maxOptionalGroups))
+	}
+	return len(groups.parents) - 1
+}
+
+// nextPosition search the next group sign in the pattern which starts at the offset, the search is limited
+// by the next parameter position, that is also returned if no group sign is found in front of it
+func (groups *optionalGroups) nextPosition(offset, nextParamPosition, patternLen int) int {
+	if len(groups.starts) == 0 {
+		return nextParamPosition
+	}
+	limit := nextParamPosition
+	if limit == -1 {
+		limit = patternLen
+	}
+	for i := 1; i < limit; i++ {
+		if groups.starts[offset+i] || groups.ends[offset+i] {
+			return i
+		}
+	}
+	return nextParamPosition
+}
+
+// buildRouteVariants expands the optional groups into all possible variants of the route,
+// the most specific variants with the most groups come first
+func buildRouteVariants(segs []*routeSegment, segGroups [][]int, parents []int) []routeVariant {
+	// collect the group combinations, a nested group can only exist together with its enclosing group
+	var masks []int
+	for mask := 1<<len(parents) - 1; mask >= 0; mask-- {
+		valid := true
+		for group, parent := range parents {
+			if mask&(1<<group) != 0 && parent != -1 && mask&(1<<parent) == 0 {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			masks = append(masks, mask)
+		}
+	}
+	sort.SliceStable(masks, func(i, j int) bool {
+		return bits.OnesCount(uint(masks[i])) > bits.OnesCount(uint(masks[j]))
+	})
+
+	variants := make([]routeVariant, len(masks))
+	for v, mask := range masks {
+		variant := &variants[v]
+		paramPosition := -1
+		for i, seg := range segs {
+			if seg.IsParam {
+				paramPosition++
+			}
+			included := true
+			for _, group := range segGroups[i] {
+				if mask&(1<<group) == 0 {
+					included = false
+					break
+				}
+			}
+			if !included {
+				continue
+			}
+			if seg.IsParam {
+				variant.parser.segs = append(variant.parser.segs, &routeSegment{
+					ParamName:   seg.ParamName,
+					IsParam:     true,
+					IsOptional:  seg.IsOptional,
+					IsGreedy:    seg.IsGreedy,
+					Constraints: seg.Constraints,
+				})
+				variant.parser.params = append(variant.parser.params, seg.ParamName)
+				variant.paramPositions = append(variant.paramPositions, paramPosition)
+				continue
+			}
+			// merge successive constant parts
+			if n := len(variant.parser.segs); n > 0 && !variant.parser.segs[n-1].IsParam {
+				variant.parser.segs[n-1].Const += seg.Const
+				variant.parser.segs[n-1].Length = len(variant.parser.segs[n-1].Const)
+				continue
+			}
+			variant.parser.segs = append(variant.parser.segs, &routeSegment{Const: seg.Const, Length: seg.Length})
+		}
+		if len(variant.parser.segs) > 0 {
+			variant.parser.segs[len(variant.parser.segs)-1].IsLast = true
+		}
+		variant.parser.segs = addParameterMetaInfo(variant.parser.segs)
+	}
+	return variants
+}
+
 // analyseConstantPart find the end of the constant part and create the route segment
 func (routeParser *routeParser) analyseConstantPart(pattern string, nextParamPosition int) (string, *routeSegment) {
 	// handle the constant part
@@ -177,18 +379,40 @@ func (routeParser *routeParser) analyseParameterPart(pattern string) (string, *r
 	isPlusParam := pattern[0] == plusParam
 	parameterEndPosition := findNextNonEscapedCharsetPosition(pattern[1:], parameterEndChars)
 
+	var constraints []*paramConstraint
+	paramNameEnd := -1
+
 	// handle wildcard end
 	if isWildCard || isPlusParam {
 		parameterEndPosition = 0
 	} else if parameterEndPosition == -1 {
 		parameterEndPosition = len(pattern) - 1
-	} else if !isInCharset(pattern[parameterEndPosition+1], parameterDelimiterChars) {
+	} else if pattern[parameterEndPosition+1] == paramConstraintStart {
+		// the parameter name ends in front of the constraint part
+		paramNameEnd = parameterEndPosition + 1
+		constraintEnd := findConstraintEnd(pattern[paramNameEnd:])
+		if constraintEnd == -1 {
+			panic(fmt.Sprintf("route: unclosed constraint in %s\n", pattern))
+		}
+		constraintEnd += paramNameEnd
+		constraints = parseConstraints(pattern[paramNameEnd+1 : constraintEnd])
+		parameterEndPosition = constraintEnd
+		// optional sign after the constraint part
+		if len(pattern) > parameterEndPosition+1 && pattern[parameterEndPosition+1] == optionalParam {
+			parameterEndPosition++
+		}
+	} else if pattern[parameterEndPosition+1] == optionalParam {
 		parameterEndPosition++
 	}
 	// cut params part
 	processedPart := pattern[0 : parameterEndPosition+1]
 
-	paramName := RemoveEscapeChar(GetTrimmedParam(processedPart))
+	var paramName string
+	if paramNameEnd != -1 {
+		paramName = RemoveEscapeChar(GetTrimmedParam(pattern[:paramNameEnd]))
+	} else {
+		paramName = RemoveEscapeChar(GetTrimmedParam(processedPart))
+	}
 	// add access iterator to wildcard and plus
 	if isWildCard {
 		routeParser.wildCardCount++
@@ -199,10 +423,11 @@ func (routeParser *routeParser) analyseParameterPart(pattern string) (string, *r
 	}
 
 	return processedPart, &routeSegment{
-		ParamName:  paramName,
-		IsParam:    true,
-		IsOptional: isWildCard || pattern[parameterEndPosition] == optionalParam,
-		IsGreedy:   isWildCard || isPlusParam,
+		ParamName:   paramName,
+		IsParam:     true,
+		IsOptional:  isWildCard || pattern[parameterEndPosition] == optionalParam,
+		IsGreedy:    isWildCard || isPlusParam,
+		Constraints: constraints,
 	}
 }
 
@@ -249,6 +474,9 @@ func findNextNonEscapedCharsetPosition(search string, charset []byte) int {
 
 // getMatch parses the passed url and tries to match it against the route segments and determine the parameter positions
 func (routeParser *routeParser) getMatch(detectionPath, path string, params *[maxParams]string, partialCheck bool) bool {
+	if len(routeParser.variants) > 0 {
+		return routeParser.getVariantMatch(detectionPath, path, params, partialCheck)
+	}
 	var i, paramsIterator, partLen int
 	for _, segment := range routeParser.segs {
 		partLen = len(detectionPath)
@@ -270,6 +498,10 @@ func (routeParser *routeParser) getMatch(detectionPath, path string, params *[ma
 			}
 			// take over the params positions
 			params[paramsIterator] = path[:i]
+			// the parameter value must fulfill all constraints, otherwise the next route is tried
+			if i > 0 && !segment.checkConstraints(params[paramsIterator]) {
+				return false
+			}
 			paramsIterator++
 		}
 
@@ -285,6 +517,25 @@ func (routeParser *routeParser) getMatch(detectionPath, path string, params *[ma
 	return true
 }
 
+// getVariantMatch tries to match the variants of a route with optional groups, the parameters
+// of the missing groups are returned as empty values
+func (routeParser *routeParser) getVariantMatch(detectionPath, path string, params *[maxParams]string, partialCheck bool) bool {
+	var values [maxParams]string
+	for _, variant := range routeParser.variants {
+		if !variant.parser.getMatch(detectionPath, path, &values, partialCheck) {
+			continue
+		}
+		for i := range routeParser.params {
+			params[i] = ""
+		}
+		for i, pos := range variant.paramPositions {
+			params[pos] = values[i]
+		}
+		return true
+	}
+	return false
+}
+
 // findParamLen for the expressjs wildcard behavior (right to left greedy)
 // look at the other segments and take what is left for the wildcard from right to left
 func findParamLen(s string, segment *routeSegment) int {
@@ -368,3 +619,258 @@ func RemoveEscapeChar(word string) string {
 	}
 	return word
 }
+
+// constraintID identifies the type of a parameter constraint
+type constraintID int
+
+// supported parameter constraints
+const (
+	intConstraint        constraintID = iota // int
+	boolConstraint                           // bool
+	floatConstraint                          // float
+	alphaConstraint                          // alpha
+	guidConstraint                           // guid
+	minLenConstraint                         // minLen(5)
+	maxLenConstraint                         // maxLen(5)
+	lenConstraint                            // len(5)
+	betweenLenConstraint                     // betweenLen(2,5)
+	minConstraint                            // min(5)
+	maxConstraint                            // max(5)
+	rangeConstraint                          // range(2,5)
+	datetimeConstraint                       // datetime(2006-01-02)
+	regexConstraint                          // regex(^[a-z]+$)
+)
+
+// constraintDefinition describes the name and the expected data of a constraint
+type constraintDefinition struct {
+	id        constraintID
+	intParams int  // number of integer values in the data
+	rawData   bool // the data is taken as it is, without splitting and conversion
+}
+
+// constraintDefinitions contains all known constraints, the keys are lower case
+// so that the constraint names are case-insensitive
+var constraintDefinitions = map[string]constraintDefinition{
+	"int":        {id: intConstraint},
+	"bool":       {id: boolConstraint},
+	"float":      {id: floatConstraint},
+	"alpha":      {id: alphaConstraint},
+	"guid":       {id: guidConstraint},
+	"minlen":     {id: minLenConstraint, intParams: 1},
+	"maxlen":     {id: maxLenConstraint, intParams: 1},
+	"len":        {id: lenConstraint, intParams: 1},
+	"betweenlen": {id: betweenLenConstraint, intParams: 2},
+	"min":        {id: minConstraint, intParams: 1},
+	"max":        {id: maxConstraint, intParams: 1},
+	"range":      {id: rangeConstraint, intParams: 2},
+	"datetime":   {id: datetimeConstraint, rawData: true},
+	"regex":      {id: regexConstraint, rawData: true},
+}
+
+// guidRegex is used for the guid constraint
+var guidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// paramConstraint holds a single constraint of a parameter, e.g. "min(5)"
+type paramConstraint struct {
+	ID      constraintID   // type of the constraint
+	Data    string         // raw data of the constraint, used for datetime layouts and regular expressions
+	IntData []int          // converted data for the length and number constraints
+	regex   *regexp.Regexp // compiled regular expression for the regex constraint
+}
+
+// findConstraintEnd search the end of the constraint part, the pattern must start with the constraint start sign,
+// the end sign is only accepted outside of the constraint data so that it can be used in regular expressions
+func findConstraintEnd(pattern string) int {
+	depth := 0
+	for i := 1; i < len(pattern); i++ {
+		switch pattern[i] {
+		case escapeChar:
+			// skip the escaped character
+			i++
+		case paramConstraintDataStart:
+			depth++
+		case paramConstraintDataEnd:
+			if depth > 0 {
+				depth--
+			}
+		case paramConstraintEnd:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// findConstraintDataEnd search the end of the constraint data, the pattern must start with the data start sign,
+// nested brackets are allowed so that groups can be used in regular expressions
+func findConstraintDataEnd(pattern string) int {
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case escapeChar:
+			// skip the escaped character
+			i++
+		case paramConstraintDataStart:
+			depth++
+		case paramConstraintDataEnd:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseConstraints analyses the constraint part of a parameter like "int;min(5)" and creates the constraints,
+// invalid constraints cause a panic, the same way as other invalid route registrations
+func parseConstraints(pattern string) []*paramConstraint {
+	var constraints []*paramConstraint
+	for len(pattern) > 0 {
+		nameEnd := findNextCharsetPosition(pattern, []byte{paramConstraintDataStart, paramConstraintSeparator})
+		if nameEnd == -1 {
+			nameEnd = len(pattern)
+		}
+		name, data, hasData := pattern[:nameEnd], "", false
+		pattern = pattern[nameEnd:]
+		if len(pattern) > 0 && pattern[0] == paramConstraintDataStart {
+			dataEnd := findConstraintDataEnd(pattern)
+			if dataEnd == -1 {
+				panic(fmt.Sprintf("route: unclosed data in constraint %s\n", name))
+			}
+			data, hasData, pattern = pattern[1:dataEnd], true, pattern[dataEnd+1:]
+		}
+		if len(pattern) > 0 {
+			if pattern[0] != paramConstraintSeparator {
+				panic(fmt.Sprintf("route: invalid constraint %s\n", name+pattern))
+			}
+			pattern = pattern[1:]
+		}
+		constraints = append(constraints, newParamConstraint(utils.Trim(name, ' '), data, hasData))
+	}
+	return constraints
+}
+
+// newParamConstraint creates a constraint with the given name and data and validates the data
+func newParamConstraint(name, data string, hasData bool) *paramConstraint {
+	definition, ok := constraintDefinitions[utils.ToLower(name)]
+	if !ok {
+		panic(fmt.Sprintf("route: unknown constraint %s\n", name))
+	}
+	constraint := &paramConstraint{ID: definition.id}
+	switch {
+	case definition.rawData:
+		if data == "" {
+			panic(fmt.Sprintf("route: constraint %s requires data\n", name))
+		}
+		constraint.Data = data
+		if definition.id == regexConstraint {
+			regex, err := regexp.Compile(data)
+			if err != nil {
+				panic(fmt.Sprintf("route: invalid regular expression in constraint %s: %v\n", name, err))
+			}
+			constraint.regex = regex
+		}
+	case definition.intParams > 0:
+		values := strings.Split(data, string(paramConstraintDataSep))
+		if !hasData || len(values) != definition.intParams {
+			panic(fmt.Sprintf("route: constraint %s requires %d integer value(s)\n", name, definition.intParams))
+		}
+		constraint.IntData = make([]int, len(values))
+		for i := range values {
+			value, err := strconv.Atoi(utils.Trim(values[i], ' '))
+			if err != nil {
+				panic(fmt.Sprintf("route: invalid value %q in constraint %s\n", values[i], name))
+			}
+			constraint.IntData[i] = value
+		}
+	case hasData:
+		panic(fmt.Sprintf("route: constraint %s does not accept data\n", name))
+	}
+	return constraint
+}
+
+// isValid checks whether the parameter value fulfills the constraint
+func (c *paramConstraint) isValid(value string) bool {
+	switch c.ID {
+	case intConstraint:
+		_, err := strconv.Atoi(value)
+		return err == nil
+	case boolConstraint:
+		_, err := strconv.ParseBool(value)
+		return err == nil
+	case floatConstraint:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case alphaConstraint:
+		for _, r := range value {
+			if !unicode.IsLetter(r) {
+				return false
+			}
+		}
+		return true
+	case guidConstraint:
+		return guidRegex.MatchString(value)
+	case minLenConstraint:
+		return utf8.RuneCountInString(value) >= c.IntData[0]
+	case maxLenConstraint:
+		return utf8.RuneCountInString(value) <= c.IntData[0]
+	case lenConstraint:
+		return utf8.RuneCountInString(value) == c.IntData[0]
+	case betweenLenConstraint:
+		length := utf8.RuneCountInString(value)
+		return length >= c.IntData[0] && length <= c.IntData[1]
+	case minConstraint:
+		num, err := strconv.Atoi(value)
+		return err == nil && num >= c.IntData[0]
+	case maxConstraint:
+		num, err := strconv.Atoi(value)
+		return err == nil && num <= c.IntData[0]
+	case rangeConstraint:
+		num, err := strconv.Atoi(value)
+		return err == nil && num >= c.IntData[0] && num <= c.IntData[1]
+	case datetimeConstraint:
+		_, err := time.Parse(c.Data, value)
+		return err == nil
+	case regexConstraint:
+		return c.regex.MatchString(value)
+	}
+	return false
+}
+
+// checkConstraints checks whether the parameter value fulfills all constraints of the segment
+func (segment *routeSegment) checkConstraints(value string) bool {
+	for _, constraint := range segment.Constraints {
+		if !constraint.isValid(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// toLowerRoute lowercases the route for the case-insensitive routing, the constraint parts are kept
+// untouched because regular expressions and datetime layouts are case-sensitive
+func toLowerRoute(pattern string) string {
+	if strings.IndexByte(pattern, paramConstraintStart) == -1 {
+		return utils.ToLower(pattern)
+	}
+	var sb strings.Builder
+	sb.Grow(len(pattern))
+	for len(pattern) > 0 {
+		start := findNextNonEscapedCharsetPosition(pattern, []byte{paramConstraintStart})
+		if start == -1 {
+			break
+		}
+		end := findConstraintEnd(pattern[start:])
+		if end == -1 {
+			break
+		}
+		end += start + 1
+		sb.WriteString(utils.ToLower(pattern[:start]))
+		sb.WriteString(pattern[start:end])
+		pattern = pattern[end:]
+	}
+	sb.WriteString(utils.ToLower(pattern))
+	return sb.String()
+}
@@ -120,6 +120,55 @@ func Test_Path_parseRoute(t *testing.T) {
 		params:        []string{"*1", "*2"},
 		wildCardCount: 2,
 	}, rp)
+
+	rp = parseRoute("/users/:id<int;min(1)>?/edit")
+	utils.AssertEqual(t, routeParser{
+		segs: []*routeSegment{
+			{Const: "/users/", Length: 7, HasOptionalSlash: true},
+			{IsParam: true, ParamName: "id", IsOptional: true, ComparePart: "/edit", PartCount: 1, Constraints: []*paramConstraint{
+				{ID: intConstraint},
+				{ID: minConstraint, IntData: []int{1}},
+			}},
+			{Const: "/edit", Length: 5, IsLast: true},
+		},
+		params: []string{"id"},
+	}, rp)
+}
+
+// go test -race -run Test_Path_parseRoute_OptionalGroups
+func Test_Path_parseRoute_OptionalGroups(t *testing.T) {
+	t.Parallel()
+	rp := parseRoute("/docs(/:lang)?/:page(.:format)?")
+	utils.AssertEqual(t, []string{"lang", "page", "format"}, rp.params)
+	utils.AssertEqual(t, 4, len(rp.variants))
+	utils.AssertEqual(t, []int{0, 1, 2}, rp.variants[0].paramPositions)
+	utils.AssertEqual(t, "/docs/", rp.variants[0].parser.segs[0].Const)
+	utils.AssertEqual(t, []int{1}, rp.variants[3].paramPositions)
+
+	defer func() {
+		utils.AssertEqual(t, "route: too many optional groups, the maximum is 8\n", fmt.Sprintf("%v", recover()))
+	}()
+	parseRoute("/(a)?(b)?(c)?(d)?(e)?(f)?(g)?(h)?(i)?")
+}
+
+// go test -race -run Test_Path_parseRoute_InvalidConstraints
+func Test_Path_parseRoute_InvalidConstraints(t *testing.T) {
+	t.Parallel()
+	testCase := func(pattern, expected string) {
+		defer func() {
+			utils.AssertEqual(t, expected, fmt.Sprintf("%v", recover()), pattern)
+		}()
+		parseRoute(pattern)
+	}
+	testCase("/:id<unknown>", "route: unknown constraint unknown\n")
+	testCase("/:id<int", "route: unclosed constraint in :id<int\n")
+	testCase("/:id<min>", "route: constraint min requires 1 integer value(s)\n")
+	testCase("/:id<min(a)>", "route: invalid value \"a\" in constraint min\n")
+	testCase("/:id<range(1)>", "route: constraint range requires 2 integer value(s)\n")
+	testCase("/:id<int(5)>", "route: constraint int does not accept data\n")
+	testCase("/:id<regex()>", "route: constraint regex requires data\n")
+	testCase("/:id<regex(a(b)>", "route: unclosed constraint in :id<regex(a(b)>\n")
+	testCase("/:id<int;regex([a-z)>", "route: invalid regular expression in constraint regex: error parsing regexp: missing closing ]: `[a-z`\n")
 }
 
 // go test -race -run Test_Path_matchParams
@@ -404,6 +453,66 @@ func Test_Path_matchParams(t *testing.T) {
 		{url: "/api/joker", params: nil, match: false},
 		{url: "/api", params: nil, match: false},
 	})
+	testCase("/users/:id<int>", []testparams{
+		{url: "/users/42", params: []string{"42"}, match: true},
+		{url: "/users/-1", params: []string{"-1"}, match: true},
+		{url: "/users/me", params: nil, match: false},
+		{url: "/users/", params: nil, match: false},
+	})
+	testCase("/files/:name<regex(^[a-z]+\\.png$)>", []testparams{
+		{url: "/files/cat.png", params: []string{"cat.png"}, match: true},
+		{url: "/files/cat.jpg", params: nil, match: false},
+		{url: "/files/Cat.png", params: nil, match: false},
+	})
+	testCase("/report/:d<datetime(2006-01-02)>/:n<min(5)>", []testparams{
+		{url: "/report/2022-04-19/5", params: []string{"2022-04-19", "5"}, match: true},
+		{url: "/report/2022-04-19/4", params: nil, match: false},
+		{url: "/report/19.04.2022/5", params: nil, match: false},
+	})
+	testCase("/api/:id<int>?", []testparams{
+		{url: "/api", params: []string{""}, match: true},
+		{url: "/api/12", params: []string{"12"}, match: true},
+		{url: "/api/abc", params: nil, match: false},
+	})
+	testCase("/:flag<bool>/:price<float>/:name<alpha;betweenLen(2,4)>", []testparams{
+		{url: "/true/1.5/abc", params: []string{"true", "1.5", "abc"}, match: true},
+		{url: "/yes/1.5/abc", params: nil, match: false},
+		{url: "/true/cheap/abc", params: nil, match: false},
+		{url: "/true/1.5/a", params: nil, match: false},
+		{url: "/true/1.5/ab1", params: nil, match: false},
+	})
+	testCase("/:id<guid>/:page<range(1,10)>/:code<len(3);maxLen(3);minLen(3)>", []testparams{
+		{url: "/ad8c4c4b-e4f4-4b0d-8f0a-3a8d7c3b0f12/10/abc", params: []string{"ad8c4c4b-e4f4-4b0d-8f0a-3a8d7c3b0f12", "10", "abc"}, match: true},
+		{url: "/ad8c4c4b/10/abc", params: nil, match: false},
+		{url: "/ad8c4c4b-e4f4-4b0d-8f0a-3a8d7c3b0f12/11/abc", params: nil, match: false},
+		{url: "/ad8c4c4b-e4f4-4b0d-8f0a-3a8d7c3b0f12/1/abcd", params: nil, match: false},
+	})
+	testCase("/docs(/:lang)?/intro", []testparams{
+		{url: "/docs/en/intro", params: []string{"en"}, match: true},
+		{url: "/docs/intro", params: []string{""}, match: true},
+		{url: "/docs/en/de/intro", params: nil, match: false},
+		{url: "/docs", params: nil, match: false},
+	})
+	testCase("/report(.:format)?", []testparams{
+		{url: "/report.json", params: []string{"json"}, match: true},
+		{url: "/report", params: []string{""}, match: true},
+		{url: "/report.", params: nil, match: false},
+		{url: "/reports", params: nil, match: false},
+	})
+	testCase("/shop(/:category(/:product<int>)?)?", []testparams{
+		{url: "/shop/books/12", params: []string{"books", "12"}, match: true},
+		{url: "/shop/books", params: []string{"books", ""}, match: true},
+		{url: "/shop", params: []string{"", ""}, match: true},
+		{url: "/shop/books/abc", params: nil, match: false},
+	})
+	testCase("/files(/*)?/:name", []testparams{
+		{url: "/files/a/b/c.txt", params: []string{"a/b", "c.txt"}, match: true},
+		{url: "/files/c.txt", params: []string{"", "c.txt"}, match: true},
+	})
+	testCase("/fn(x)/:id", []testparams{
+		{url: "/fn(x)/1", params: []string{"1"}, match: true},
+		{url: "/fn/1", params: nil, match: false},
+	})
 	testCase("/api/*/:param/:param2", []testparams{
 		{url: "/api/test/abc/1", params: []string{"test", "abc", "1"}, match: true},
 		{url: "/api/joker/batman", params: nil, match: false},
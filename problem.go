@@ -0,0 +1,163 @@
+package lightning
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"strconv"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// Problem is an error with the details of RFC 7807, it's rendered as application/problem+json
+// by the ProblemErrorHandler.
+//  return lightning.NewProblem(lightning.StatusForbidden, "Your balance is too low").
+//  	With("balance", 30)
+type Problem struct {
+	Type       string                 // URI reference of the problem type, defaults to "about:blank"
+	Title      string                 // Short summary of the problem type, defaults to the status message
+	Status     int                    // HTTP status code
+	Detail     string                 // Explanation of this occurrence of the problem
+	Instance   string                 // URI reference of this occurrence, defaults to the path of the request
+	Extensions map[string]interface{} // Additional members of the problem
+
+	err error // Error which caused the problem
+}
+
+// NewProblem creates a new Problem with the status code and an optional detail
+func NewProblem(status int, detail ...string) *Problem {
+	p := &Problem{Status: status}
+	if len(detail) > 0 {
+		p.Detail = detail[0]
+	}
+	return p
+}
+
+// With adds an extension member to the problem
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Error returns the detail of the problem, otherwise the title
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.title()
+}
+
+// Unwrap returns the error which caused the problem
+func (p *Problem) Unwrap() error {
+	return p.err
+}
+
+// MarshalJSON encodes the problem with its extension members at the top level
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = "about:blank"
+	}
+	members["title"] = p.title()
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// title returns the title of the problem, otherwise the status message
+func (p *Problem) title() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return utils.StatusMessage(p.Status)
+}
+
+// ProblemFromError converts the error to a problem, wrapped errors are unwrapped to find the status.
+// Validation errors list their fields in the "errors" member, the details of unknown errors are hidden
+// behind a 500 Internal Server Error.
+func ProblemFromError(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		// Copy the problem, it can be a shared variable
+		p := *problem
+		return &p
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		p := NewProblem(StatusBadRequest, validationErr.Error()).With("errors", validationErr.Fields)
+		p.err = err
+		return p
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		p := NewProblem(e.Code)
+		if e.Message != utils.StatusMessage(e.Code) {
+			p.Detail = e.Message
+		}
+		p.err = err
+		return p
+	}
+	p := NewProblem(StatusInternalServerError)
+	p.err = err
+	return p
+}
+
+// ProblemErrorHandler renders the errors as problems of RFC 7807, the format is negotiated
+// with the Accept header: application/problem+json, HTML or plain text.
+//  app := lightning.New(lightning.Config{ErrorHandler: lightning.ProblemErrorHandler})
+func ProblemErrorHandler(req *Request, res *Response, err error) error {
+	p := ProblemFromError(err)
+	if p.Instance == "" {
+		p.Instance = req.OriginalURL()
+	}
+	res.Status(p.Status)
+
+	switch req.Accepts(MIMEApplicationProblemJSON, MIMEApplicationJSON, MIMETextHTML, MIMETextPlain) {
+	case MIMETextHTML:
+		res.Header.Set(HeaderContentType, MIMETextHTMLCharsetUTF8)
+		title := html.EscapeString(strconv.Itoa(p.Status) + " " + p.title())
+		body := "<!DOCTYPE html><html><head><title>" + title + "</title></head><body><h1>" + title + "</h1>"
+		if p.Detail != "" {
+			body += "<p>" + html.EscapeString(p.Detail) + "</p>"
+		}
+		return res.String(body + "</body></html>")
+	case MIMETextPlain:
+		res.Header.Set(HeaderContentType, MIMETextPlainCharsetUTF8)
+		return res.String(p.Error())
+	}
+	raw, encodeErr := req.ctx.app.config.JSONEncoder(p)
+	if encodeErr != nil {
+		return encodeErr
+	}
+	res.Header.Set(HeaderContentType, MIMEApplicationProblemJSON)
+	return res.Bytes(raw)
+}
+
+// errorStatus returns the status code of the error, wrapped errors are unwrapped
+func errorStatus(err error) int {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem.Status
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return StatusBadRequest
+	}
+	return StatusInternalServerError
+}
@@ -0,0 +1,148 @@
+package lightning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// go test -run Test_Problem_ErrorHandler
+func Test_Problem_ErrorHandler(t *testing.T) {
+	t.Parallel()
+	app := New(Config{ErrorHandler: ProblemErrorHandler})
+	app.Get("/problem", func(req *Request, res *Response) error {
+		p := NewProblem(StatusForbidden, "Your balance is too low").With("balance", 30)
+		p.Type = "https://example.com/probs/out-of-credit"
+		return p
+	})
+	app.Get("/wrapped", func(req *Request, res *Response) error {
+		return fmt.Errorf("load user: %w", NewError(StatusNotFound, "user 1 not found"))
+	})
+	app.Get("/internal", func(req *Request, res *Response) error {
+		return errors.New("database password is wrong")
+	})
+	app.Post("/users", func(req *Request, res *Response) error {
+		return req.Bind(&struct {
+			Name string `json:"name" validate:"required"`
+		}{})
+	})
+
+	testCases := []struct {
+		method  string
+		url     string
+		problem map[string]interface{}
+	}{
+		{method: MethodGet, url: "/problem", problem: map[string]interface{}{
+			"type": "https://example.com/probs/out-of-credit", "title": "Forbidden", "status": float64(403),
+			"detail": "Your balance is too low", "instance": "/problem", "balance": float64(30),
+		}},
+		{method: MethodGet, url: "/wrapped?id=1", problem: map[string]interface{}{
+			"type": "about:blank", "title": "Not Found", "status": float64(404),
+			"detail": "user 1 not found", "instance": "/wrapped?id=1",
+		}},
+		{method: MethodGet, url: "/internal", problem: map[string]interface{}{
+			"type": "about:blank", "title": "Internal Server Error", "status": float64(500), "instance": "/internal",
+		}},
+		{method: MethodPost, url: "/users", problem: map[string]interface{}{
+			"type": "about:blank", "title": "Bad Request", "status": float64(400),
+			"detail": "validation failed: name is required", "instance": "/users",
+			"errors": []interface{}{map[string]interface{}{"field": "name", "rule": "required"}},
+		}},
+	}
+
+	for _, tc := range testCases {
+		resp, err := app.Test(httptest.NewRequest(tc.method, tc.url, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, int(tc.problem["status"].(float64)), resp.StatusCode, tc.url)
+		utils.AssertEqual(t, MIMEApplicationProblemJSON, resp.Header.Get(HeaderContentType), tc.url)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		var problem map[string]interface{}
+		utils.AssertEqual(t, nil, json.Unmarshal(body, &problem))
+		utils.AssertEqual(t, tc.problem, problem, tc.url)
+	}
+}
+
+// go test -run Test_Problem_ErrorHandler_Negotiation
+func Test_Problem_ErrorHandler_Negotiation(t *testing.T) {
+	t.Parallel()
+	app := New(Config{ErrorHandler: ProblemErrorHandler})
+	app.Get("/", func(req *Request, res *Response) error {
+		return NewProblem(StatusConflict, "<b>name</b> is taken")
+	})
+
+	testCases := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{accept: "text/html,application/xhtml+xml", contentType: MIMETextHTMLCharsetUTF8,
+			body: "<!DOCTYPE html><html><head><title>409 Conflict</title></head><body><h1>409 Conflict</h1><p>&lt;b&gt;name&lt;/b&gt; is taken</p></body></html>"},
+		{accept: "text/plain", contentType: MIMETextPlainCharsetUTF8, body: "<b>name</b> is taken"},
+		{accept: "application/json", contentType: MIMEApplicationProblemJSON,
+			body: `{"detail":"\u003cb\u003ename\u003c/b\u003e is taken","instance":"/","status":409,"title":"Conflict","type":"about:blank"}`},
+		{accept: "image/png", contentType: MIMEApplicationProblemJSON,
+			body: `{"detail":"\u003cb\u003ename\u003c/b\u003e is taken","instance":"/","status":409,"title":"Conflict","type":"about:blank"}`},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(MethodGet, "/", nil)
+		req.Header.Set(HeaderAccept, tc.accept)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusConflict, resp.StatusCode)
+		utils.AssertEqual(t, tc.contentType, resp.Header.Get(HeaderContentType), tc.accept)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.body, string(body), tc.accept)
+	}
+}
+
+// go test -run Test_ProblemFromError
+func Test_ProblemFromError(t *testing.T) {
+	t.Parallel()
+	shared := NewProblem(StatusTeapot)
+	p := ProblemFromError(fmt.Errorf("brew: %w", shared))
+	p.Instance = "/coffee"
+	utils.AssertEqual(t, "", shared.Instance)
+	utils.AssertEqual(t, StatusTeapot, p.Status)
+	utils.AssertEqual(t, "I'm a teapot", p.Error())
+
+	cause := errors.New("boom")
+	p = ProblemFromError(cause)
+	utils.AssertEqual(t, StatusInternalServerError, p.Status)
+	utils.AssertEqual(t, "", p.Detail)
+	utils.AssertEqual(t, true, errors.Is(p, cause))
+
+	p = ProblemFromError(ErrUnauthorized)
+	utils.AssertEqual(t, StatusUnauthorized, p.Status)
+	utils.AssertEqual(t, "", p.Detail)
+}
+
+// go test -run Test_DefaultErrorHandler_Unwrap
+func Test_DefaultErrorHandler_Unwrap(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		return fmt.Errorf("find user: %w", ErrNotFound)
+	})
+	app.Get("/problem", func(req *Request, res *Response) error {
+		return NewProblem(StatusGone, "user was deleted")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "find user: Not Found", string(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/problem", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusGone, resp.StatusCode)
+}
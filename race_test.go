@@ -0,0 +1,7 @@
+//go:build race
+// +build race
+
+package lightning
+
+// raceEnabled reports whether the tests run with the race detector, which allocates on its own
+const raceEnabled = true
@@ -0,0 +1,205 @@
+package lightning
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// Renderer encodes the data of res.Negotiate for a media type
+type Renderer = func(data interface{}) ([]byte, error)
+
+// Decoder decodes a request body of a media type for BodyParser
+type Decoder = func(body []byte, out interface{}) error
+
+// mediaRenderer is a renderer of the registry
+type mediaRenderer struct {
+	mediaType string
+	render    Renderer
+}
+
+// RegisterRenderer adds a renderer for the media type to res.Negotiate or replaces the existing one.
+// New media types are preferred least if the client accepts several types equally.
+// The registry is copied on every change, so renderers can be registered while the server is running.
+//  app.RegisterRenderer("application/yaml", yaml.Marshal)
+func (app *App) RegisterRenderer(mediaType string, renderer Renderer) {
+	mediaType = parseMediaType(mediaType)
+	if mediaType == "" {
+		panic("render: media type must not be empty\n")
+	}
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	current, _ := app.renderers.Load().([]mediaRenderer)
+	renderers := make([]mediaRenderer, len(current), len(current)+1)
+	copy(renderers, current)
+	for i := range renderers {
+		if renderers[i].mediaType == mediaType {
+			renderers[i].render = renderer
+			app.renderers.Store(renderers)
+			return
+		}
+	}
+	app.renderers.Store(append(renderers, mediaRenderer{mediaType: mediaType, render: renderer}))
+}
+
+// RegisterDecoder adds a decoder for the media type to BodyParser or replaces the existing one.
+// Like the renderers, the decoders are copied on every change.
+//  app.RegisterDecoder("application/yaml", yaml.Unmarshal)
+func (app *App) RegisterDecoder(mediaType string, decoder Decoder) {
+	mediaType = parseMediaType(mediaType)
+	if mediaType == "" {
+		panic("render: media type must not be empty\n")
+	}
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	current, _ := app.decoders.Load().(map[string]Decoder)
+	decoders := make(map[string]Decoder, len(current)+1)
+	for key, value := range current {
+		decoders[key] = value
+	}
+	decoders[mediaType] = decoder
+	app.decoders.Store(decoders)
+}
+
+// registerDefaultCodecs registers JSON, XML, MessagePack and plain text,
+// JSON uses the encoder and decoder of the config
+func (app *App) registerDefaultCodecs() {
+	jsonRenderer := func(data interface{}) ([]byte, error) {
+		return app.config.JSONEncoder(data)
+	}
+	jsonDecoder := func(body []byte, out interface{}) error {
+		return app.config.JSONDecoder(body, out)
+	}
+	app.RegisterRenderer(MIMEApplicationJSON, jsonRenderer)
+	app.RegisterDecoder(MIMEApplicationJSON, jsonDecoder)
+	for _, mediaType := range []string{MIMEApplicationXML, MIMETextXML} {
+		app.RegisterRenderer(mediaType, xml.Marshal)
+		app.RegisterDecoder(mediaType, xml.Unmarshal)
+	}
+	for _, mediaType := range []string{MIMEApplicationMsgPack, "application/x-msgpack"} {
+		app.RegisterRenderer(mediaType, marshalMsgPack)
+		app.RegisterDecoder(mediaType, unmarshalMsgPack)
+	}
+	app.RegisterRenderer(MIMETextPlain, renderText)
+	app.RegisterDecoder(MIMETextPlain, decodeText)
+}
+
+// renderText renders strings, bytes, errors and fmt.Stringer as they are, other values are formatted with %v
+func renderText(data interface{}) ([]byte, error) {
+	switch val := data.(type) {
+	case string:
+		return []byte(val), nil
+	case []byte:
+		return val, nil
+	case encoding.TextMarshaler:
+		return val.MarshalText()
+	default:
+		return []byte(fmt.Sprint(val)), nil
+	}
+}
+
+// decodeText decodes the body into a string, a byte slice or an encoding.TextUnmarshaler
+func decodeText(body []byte, out interface{}) error {
+	switch val := out.(type) {
+	case *string:
+		*val = string(body)
+	case *[]byte:
+		*val = append((*val)[:0], body...)
+	case encoding.TextUnmarshaler:
+		return val.UnmarshalText(body)
+	default:
+		return ErrUnprocessableEntity
+	}
+	return nil
+}
+
+// Negotiate renders the data with the renderer which fits the Accept header best,
+// ErrNotAcceptable is returned if no renderer is accepted.
+// JSON, XML, MessagePack and plain text are registered by default, see App.RegisterRenderer.
+//  return res.Negotiate(user)
+func (res *Response) Negotiate(data interface{}) error {
+	res.ctx.Vary(HeaderAccept)
+	renderer := negotiateRenderer(res.ctx.Get(HeaderAccept), res.ctx.app.renderers.Load().([]mediaRenderer))
+	if renderer == nil {
+		return ErrNotAcceptable
+	}
+	raw, err := renderer.render(data)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(renderer.mediaType, "text/") {
+		res.ctx.fasthttp.Response.Header.SetContentType(renderer.mediaType + "; charset=utf-8")
+	} else {
+		res.ctx.fasthttp.Response.Header.SetContentType(renderer.mediaType)
+	}
+	res.ctx.fasthttp.Response.SetBodyRaw(raw)
+	return nil
+}
+
+// negotiateRenderer returns the renderer with the highest quality in the Accept header,
+// the order of the registry breaks ties and the first renderer is used without an Accept header
+func negotiateRenderer(accept string, renderers []mediaRenderer) *mediaRenderer {
+	if len(renderers) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(accept) == "" {
+		return &renderers[0]
+	}
+	var (
+		best        *mediaRenderer
+		bestQuality float64
+	)
+	for i := range renderers {
+		if quality := acceptQuality(accept, renderers[i].mediaType); quality > bestQuality {
+			best, bestQuality = &renderers[i], quality
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality of the most specific range of the Accept header which matches the media type
+func acceptQuality(accept, mediaType string) float64 {
+	quality, specificity := 0.0, -1
+	for _, spec := range strings.Split(accept, ",") {
+		params := strings.Split(spec, ";")
+		accepted := strings.TrimSpace(utils.ToLower(params[0]))
+
+		var s int
+		switch {
+		case accepted == mediaType:
+			s = 2
+		case strings.HasSuffix(accepted, "/*") && strings.HasPrefix(mediaType, accepted[:len(accepted)-1]):
+			s = 1
+		case accepted == "*/*" || accepted == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if len(param) > 2 && (param[0] == 'q' || param[0] == 'Q') && param[1] == '=' {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = value
+				}
+			}
+		}
+		quality, specificity = q, s
+	}
+	return quality
+}
+
+// parseMediaType returns the lowercase media type without parameters
+func parseMediaType(contentType string) string {
+	if semiColon := strings.IndexByte(contentType, ';'); semiColon != -1 {
+		contentType = contentType[:semiColon]
+	}
+	return strings.TrimSpace(utils.ToLower(contentType))
+}
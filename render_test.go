@@ -0,0 +1,227 @@
+package lightning
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp"
+)
+
+type renderPayload struct {
+	XMLName xml.Name `json:"-" xml:"payload"`
+	Name    string   `json:"name" xml:"name"`
+	Tags    []string `json:"tags" xml:"tag"`
+}
+
+func (p renderPayload) String() string {
+	return p.Name
+}
+
+// go test -run Test_Response_Negotiate
+func Test_Response_Negotiate(t *testing.T) {
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		return res.Negotiate(renderPayload{Name: "john", Tags: []string{"admin"}})
+	})
+
+	msgPack, err := marshalMsgPack(renderPayload{Name: "john", Tags: []string{"admin"}})
+	utils.AssertEqual(t, nil, err)
+
+	testCases := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", MIMEApplicationJSON, `{"name":"john","tags":["admin"]}`},
+		{"*/*", MIMEApplicationJSON, `{"name":"john","tags":["admin"]}`},
+		{"application/xml", MIMEApplicationXML, `<payload><name>john</name><tag>admin</tag></payload>`},
+		{"text/xml, application/json;q=0.9", MIMETextXMLCharsetUTF8, `<payload><name>john</name><tag>admin</tag></payload>`},
+		{"application/msgpack", MIMEApplicationMsgPack, string(msgPack)},
+		{"text/*", MIMETextXMLCharsetUTF8, `<payload><name>john</name><tag>admin</tag></payload>`},
+		{"text/plain, */*;q=0.1", MIMETextPlainCharsetUTF8, "john"},
+		{"application/json;q=0.5, text/plain;q=0.8", MIMETextPlainCharsetUTF8, "john"},
+		{"application/*;q=0.2, application/msgpack;q=0, text/html", MIMEApplicationJSON, `{"name":"john","tags":["admin"]}`},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(MethodGet, "/", nil)
+		if tc.accept != "" {
+			req.Header.Set(HeaderAccept, tc.accept)
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, tc.accept)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, tc.accept)
+		utils.AssertEqual(t, tc.contentType, resp.Header.Get(HeaderContentType), tc.accept)
+		utils.AssertEqual(t, HeaderAccept, resp.Header.Get(HeaderVary), tc.accept)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, tc.accept)
+		utils.AssertEqual(t, tc.body, string(body), tc.accept)
+	}
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Set(HeaderAccept, "image/png, application/json;q=0")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNotAcceptable, resp.StatusCode)
+}
+
+// go test -run Test_App_RegisterRenderer
+func Test_App_RegisterRenderer(t *testing.T) {
+	app := New()
+	app.RegisterRenderer("text/csv; charset=utf-8", func(data interface{}) ([]byte, error) {
+		p := data.(renderPayload)
+		return []byte(p.Name + "," + strings.Join(p.Tags, ";")), nil
+	})
+	app.RegisterRenderer(MIMEApplicationJSON, func(data interface{}) ([]byte, error) {
+		return []byte("{}"), nil
+	})
+	app.Get("/", func(req *Request, res *Response) error {
+		return res.Negotiate(renderPayload{Name: "john", Tags: []string{"admin", "dev"}})
+	})
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Set(HeaderAccept, "text/csv")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "text/csv; charset=utf-8", resp.Header.Get(HeaderContentType))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john,admin;dev", string(body))
+
+	// the replaced renderer keeps its position
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, MIMEApplicationJSON, resp.Header.Get(HeaderContentType))
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "{}", string(body))
+
+	defer func() {
+		utils.AssertEqual(t, "render: media type must not be empty\n", recover())
+	}()
+	app.RegisterRenderer(" ", nil)
+}
+
+// go test -run Test_Ctx_BodyParser_Decoders
+func Test_Ctx_BodyParser_Decoders(t *testing.T) {
+	app := New()
+	app.RegisterDecoder("text/csv", func(body []byte, out interface{}) error {
+		values := strings.Split(string(body), ",")
+		p := out.(*renderPayload)
+		p.Name, p.Tags = values[0], values[1:]
+		return nil
+	})
+	app.Post("/", func(req *Request, res *Response) error {
+		p := new(renderPayload)
+		if err := req.ctx.BodyParser(p); err != nil {
+			return err
+		}
+		return res.String(p.Name + ":" + strings.Join(p.Tags, ","))
+	})
+
+	payload := renderPayload{Name: "john", Tags: []string{"admin", "dev"}}
+	jsonBody, err := json.Marshal(payload)
+	utils.AssertEqual(t, nil, err)
+	xmlBody, err := xml.Marshal(payload)
+	utils.AssertEqual(t, nil, err)
+	msgPackBody, err := marshalMsgPack(payload)
+	utils.AssertEqual(t, nil, err)
+
+	bodies := map[string][]byte{
+		MIMEApplicationJSON:                       jsonBody,
+		"application/vnd.api+json; charset=utf-8": jsonBody,
+		MIMEApplicationXML:                        xmlBody,
+		MIMETextXMLCharsetUTF8:                    xmlBody,
+		MIMEApplicationMsgPack:                    msgPackBody,
+		"application/x-msgpack":                   msgPackBody,
+		"text/csv":                                []byte("john,admin,dev"),
+	}
+	for contentType, body := range bodies {
+		req := httptest.NewRequest(MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(HeaderContentType, contentType)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, contentType)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, contentType)
+		result, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, contentType)
+		utils.AssertEqual(t, "john:admin,dev", string(result), contentType)
+	}
+
+	// plain text can't be decoded into a struct
+	req := httptest.NewRequest(MethodPost, "/", strings.NewReader("john"))
+	req.Header.Set(HeaderContentType, MIMETextPlain)
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusUnprocessableEntity, resp.StatusCode)
+
+	var text string
+	utils.AssertEqual(t, nil, decodeText([]byte("john"), &text))
+	utils.AssertEqual(t, "john", text)
+}
+
+// go test -race -run Test_App_RegisterCodecs_Concurrent
+func Test_App_RegisterCodecs_Concurrent(t *testing.T) {
+	app := New()
+	app.Post("/", func(req *Request, res *Response) error {
+		var p renderPayload
+		if err := req.ctx.BodyParser(&p); err != nil {
+			return err
+		}
+		return res.Negotiate(p)
+	})
+
+	// Codecs can be registered while requests are handled
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			mediaType := "application/x-test" + strconv.Itoa(i)
+			app.RegisterRenderer(mediaType, renderText)
+			app.RegisterDecoder(mediaType, decodeText)
+		}(i)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(MethodPost, "/", strings.NewReader(`{"name":"john"}`))
+			req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+			req.Header.Set(HeaderAccept, MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+	utils.AssertEqual(t, 6+10, len(app.renderers.Load().([]mediaRenderer)))
+	utils.AssertEqual(t, 6+10, len(app.decoders.Load().(map[string]Decoder)))
+}
+
+// go test -run Test_AcceptQuality
+func Test_AcceptQuality(t *testing.T) {
+	utils.AssertEqual(t, 1.0, acceptQuality("application/json", MIMEApplicationJSON))
+	utils.AssertEqual(t, 0.5, acceptQuality("Application/JSON; Q=0.5", MIMEApplicationJSON))
+	utils.AssertEqual(t, 0.0, acceptQuality("application/json;q=0, */*", MIMEApplicationJSON))
+	utils.AssertEqual(t, 0.8, acceptQuality("*/*;q=0.2, application/*;q=0.8", MIMEApplicationJSON))
+	utils.AssertEqual(t, 0.0, acceptQuality("text/*", MIMEApplicationJSON))
+}
+
+// go test -v -run=^$ -bench=Benchmark_Response_Negotiate -benchmem -count=4
+func Benchmark_Response_Negotiate(b *testing.B) {
+	app := New()
+	req, res := app.AcquireReqRes(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtxFromReqRes(req, res)
+	req.ctx.fasthttp.Request.Header.Set(HeaderAccept, "text/html, application/xhtml+xml, application/xml;q=0.9, */*;q=0.8")
+	payload := renderPayload{Name: "john", Tags: []string{"admin"}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_ = res.Negotiate(payload)
+	}
+}
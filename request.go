@@ -141,6 +141,18 @@ func (req *Request) GetCookie(key string, defaultValue ...string) string {
 	return defaultString(utils.UnsafeString(req.ctx.fasthttp.Request.Header.Cookie(key)), defaultValue)
 }
 
+// SignedCookie returns the value of a cookie set with res.SetSignedCookie, the signature is verified
+// with all keys of Config.CookieSigningKeys. It returns ErrCookieNotFound if the cookie is missing
+// and ErrCookieSignature if the value was changed or signed with an unknown key.
+// The returned value is only valid within the handler. Do not store any references.
+func (req *Request) SignedCookie(name string) (string, error) {
+	signed := req.ctx.Cookies(name)
+	if signed == "" {
+		return "", ErrCookieNotFound
+	}
+	return req.ctx.app.verifyCookieValue(name, signed)
+}
+
 func (req *Request) ClearCookie(key string) {
 	req.ctx.ClearCookie(key)
 }
@@ -2,6 +2,9 @@ package lightning
 
 import (
 	"encoding/json"
+	"net"
+	"strings"
+
 	"github.com/ikidev/lightning/utils"
 	"github.com/valyala/fasthttp"
 )
@@ -71,6 +74,20 @@ func (res *Response) JSON(data interface{}) error {
 	return nil
 }
 
+// MsgPack sends a MessagePack response, types which implement msgp.Marshaler like the types
+// generated by github.com/tinylib/msgp are encoded without reflection.
+// Structs are encoded as maps with the keys of the msgpack or json tags.
+func (res *Response) MsgPack(data interface{}) error {
+	raw, err := marshalMsgPack(data)
+	if err != nil {
+		return err
+	}
+	res.ctx.fasthttp.Response.SetBodyRaw(raw)
+	res.ctx.fasthttp.Response.Header.SetContentType(MIMEApplicationMsgPack)
+	res.rType = "msgpack"
+	return nil
+}
+
 // JSONP sends a JSON response with JSONP support.
 // This method is identical to JSON, except that it opts-in to JSONP callback support.
 // By default, the callback name is simply callback.
@@ -96,32 +113,23 @@ func (res *Response) JSONP(data interface{}, callback ...string) error {
 }
 
 func (res *Response) SetCookie(cookie *Cookie) *Response {
-	fCookie := fasthttp.AcquireCookie()
-	fCookie.SetKey(cookie.Name)
-	fCookie.SetValue(cookie.Value)
-	fCookie.SetPath(cookie.Path)
-	fCookie.SetDomain(cookie.Domain)
-	fCookie.SetMaxAge(cookie.MaxAge)
-	fCookie.SetExpire(cookie.Expires)
-	fCookie.SetSecure(cookie.Secure)
-	fCookie.SetHTTPOnly(cookie.HTTPOnly)
-
-	switch utils.ToLower(cookie.SameSite) {
-	case CookieSameSiteStrictMode:
-		fCookie.SetSameSite(fasthttp.CookieSameSiteStrictMode)
-	case CookieSameSiteNoneMode:
-		fCookie.SetSameSite(fasthttp.CookieSameSiteNoneMode)
-	case CookieSameSiteDisabled:
-		fCookie.SetSameSite(fasthttp.CookieSameSiteDisabled)
-	default:
-		fCookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
-	}
-
-	res.ctx.fasthttp.Response.Header.SetCookie(fCookie)
-	fasthttp.ReleaseCookie(fCookie)
+	res.ctx.Cookie(cookie)
 	return res
 }
 
+// SetSignedCookie sets a cookie whose value is signed with the newest key of Config.CookieSigningKeys,
+// the value is readable by the client but can't be changed. Read it with req.SignedCookie.
+func (res *Response) SetSignedCookie(cookie *Cookie) error {
+	value, err := res.ctx.app.signCookieValue(cookie.Name, cookie.Value)
+	if err != nil {
+		return err
+	}
+	signed := *cookie
+	signed.Value = value
+	res.ctx.Cookie(&signed)
+	return nil
+}
+
 func (res *Response) File(file string, compress ...bool) error {
 	res.rType = "file"
 	return res.ctx.SendFile(file, compress...)
@@ -142,3 +150,59 @@ func (res *Response) Send() error {
 
 	return nil
 }
+
+// RedirectToRoute redirects to the URL of the named route with the given params, see App.URL.
+// The name is resolved by the app which routes the request, so mounted apps can redirect to all routes.
+// If not specified, status defaults to 302 Found.
+func (res *Response) RedirectToRoute(name string, params Map, status ...int) error {
+	location, err := res.ctx.router.URL(name, params)
+	if err != nil {
+		return err
+	}
+	return res.ctx.Redirect(location, status...)
+}
+
+// Upgrade switches the connection to the protocol which is requested by the Upgrade header of the client.
+// The 101 Switching Protocols response is sent after the handler returned, then fn gets the raw connection.
+// The Ctx is released before fn is called, so fn must not use the request or the response.
+// The connection is closed when fn returns. Requests without an upgrade to the protocol are rejected
+// with 426 Upgrade Required.
+//  return res.Upgrade("tunnel", func(conn net.Conn) {
+//  	_, _ = io.Copy(conn, conn)
+//  })
+func (res *Response) Upgrade(protocol string, fn func(conn net.Conn)) error {
+	header := &res.ctx.fasthttp.Request.Header
+	if !hasHeaderToken(utils.UnsafeString(header.Peek(HeaderConnection)), "upgrade") ||
+		!hasHeaderToken(utils.UnsafeString(header.Peek(HeaderUpgrade)), protocol) {
+		res.ctx.fasthttp.Response.Header.Set(HeaderUpgrade, protocol)
+		return ErrUpgradeRequired
+	}
+	res.ctx.fasthttp.Response.Header.Set(HeaderConnection, "Upgrade")
+	res.ctx.fasthttp.Response.Header.Set(HeaderUpgrade, protocol)
+	res.ctx.fasthttp.HijackSetNoResponse(false)
+	res.ctx.fasthttp.Hijack(fn)
+	return res.Status(StatusSwitchingProtocols).Send()
+}
+
+// Hijack takes over the raw connection after the handler returned, no response is sent and fn writes its own.
+// The Ctx is released before fn is called, so fn must not use the request or the response.
+// The connection is closed when fn returns.
+//  return res.Hijack(func(conn net.Conn) {
+//  	_, _ = conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+//  	tunnel(conn)
+//  })
+func (res *Response) Hijack(fn func(conn net.Conn)) error {
+	res.ctx.fasthttp.HijackSetNoResponse(true)
+	res.ctx.fasthttp.Hijack(fn)
+	return nil
+}
+
+// hasHeaderToken checks if the comma separated header contains the token, case-insensitive
+func hasHeaderToken(header, token string) bool {
+	for _, value := range strings.Split(header, ",") {
+		if utils.EqualFold(strings.TrimSpace(value), token) {
+			return true
+		}
+	}
+	return false
+}
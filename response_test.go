@@ -1,3 +1,121 @@
 package lightning
 
-// TODO implement
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// serveInmemory starts the app on an in-memory listener which is closed with the test
+func serveInmemory(t *testing.T, app *App) *fasthttputil.InmemoryListener {
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		_ = app.Listener(ln)
+	}()
+	t.Cleanup(func() {
+		_ = app.Shutdown()
+	})
+	return ln
+}
+
+// go test -run Test_Response_Upgrade
+func Test_Response_Upgrade(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+	app.Get("/echo/:name", func(req *Request, res *Response) error {
+		// the Ctx is released before the callback, the values have to be copied
+		greeting := "hello " + utils.CopyString(req.Param("name")) + "\n"
+		return res.Upgrade("echo", func(conn net.Conn) {
+			if _, err := conn.Write([]byte(greeting)); err != nil {
+				return
+			}
+			_, _ = io.Copy(conn, conn)
+		})
+	})
+
+	conn, err := serveInmemory(t, app).Dial()
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET /echo/john HTTP/1.1\r\nHost: example.com\r\nConnection: keep-alive, Upgrade\r\nUpgrade: echo\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusSwitchingProtocols, resp.StatusCode)
+	utils.AssertEqual(t, "Upgrade", resp.Header.Get(HeaderConnection))
+	utils.AssertEqual(t, "echo", resp.Header.Get(HeaderUpgrade))
+
+	line, err := br.ReadString('\n')
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "hello john\n", line)
+
+	_, err = conn.Write([]byte("ping\n"))
+	utils.AssertEqual(t, nil, err)
+	line, err = br.ReadString('\n')
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "ping\n", line)
+}
+
+// go test -run Test_Response_Upgrade_Required
+func Test_Response_Upgrade_Required(t *testing.T) {
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		return res.Upgrade("echo", func(conn net.Conn) {
+			t.Error("connection must not be upgraded")
+		})
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusUpgradeRequired, resp.StatusCode)
+	utils.AssertEqual(t, "echo", resp.Header.Get(HeaderUpgrade))
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Set(HeaderConnection, "Upgrade")
+	req.Header.Set(HeaderUpgrade, "h2c")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusUpgradeRequired, resp.StatusCode)
+}
+
+// go test -run Test_Response_Hijack
+func Test_Response_Hijack(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+	app.Add(MethodConnect, "/", func(req *Request, res *Response) error {
+		res.Status(StatusTeapot)
+		return res.Hijack(func(conn net.Conn) {
+			if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+				return
+			}
+			_, _ = io.Copy(conn, conn)
+		})
+	})
+
+	conn, err := serveInmemory(t, app).Dial()
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("CONNECT / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+
+	br := bufio.NewReader(conn)
+	status, err := br.ReadString('\n')
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "HTTP/1.1 200 Connection Established\r\n", status)
+	line, err := br.ReadString('\n')
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "\r\n", line)
+
+	_, err = conn.Write([]byte("tunnel\n"))
+	utils.AssertEqual(t, nil, err)
+	line, err = br.ReadString('\n')
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "tunnel\n", line)
+}
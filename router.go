@@ -7,7 +7,6 @@ package lightning
 import (
 	"fmt"
 	"github.com/ikidev/lightning/utils"
-	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -40,7 +39,16 @@ type Router interface {
 
 	Mount(prefix string, lighting *App) Router
 
+	Host(pattern string) Router
+
+	Version(version string, handlers ...Handler) Router
+
 	Name(name string) Router
+
+	Summary(summary string) Router
+	Tags(tags ...string) Router
+	RequestType(v interface{}) Router
+	ResponseType(status int, v interface{}) Router
 }
 
 // Route is a struct that holds all metadata for each registered handler
@@ -52,13 +60,19 @@ type Route struct {
 	root        bool        // Path equals '/'
 	path        string      // Prettified path
 	routeParser routeParser // Parameter parser
+	rawParser   routeParser // Parameter parser of the original path, used for the redirect to the fixed path
+	host        *routeHost  // Host parser, nil if the route matches every host
+	app         *App        // App which registered the route, mounted apps keep their config for their routes
 
 	// Public fields
-	Method   string    `json:"method"` // HTTP method
-	Name     string    `json:"name"`   // Route's name
-	Path     string    `json:"path"`   // Original registered route path
-	Params   []string  `json:"params"` // Case sensitive param keys
-	Handlers []Handler `json:"-"`      // Ctx handlers
+	Method   string    `json:"method"`            // HTTP method
+	Name     string    `json:"name"`              // Route's name
+	Path     string    `json:"path"`              // Original registered route path
+	Host     string    `json:"host,omitempty"`    // Original registered host pattern
+	Version  string    `json:"version,omitempty"` // API version of the route
+	Params   []string  `json:"params"`            // Case sensitive param keys
+	Meta     RouteMeta `json:"meta"`              // Documentation of the route
+	Handlers []Handler `json:"-"`                 // Ctx handlers
 }
 
 func (r *Route) match(detectionPath, path string, params *[maxParams]string) (match bool) {
@@ -74,8 +88,8 @@ func (r *Route) match(detectionPath, path string, params *[maxParams]string) (ma
 		}
 		return true
 	}
-	// Does this route have parameters
-	if len(r.Params) > 0 {
+	// Does this route have parameters or optional groups
+	if len(r.Params) > 0 || len(r.routeParser.variants) > 0 {
 		// Match params
 		if match := r.routeParser.getMatch(detectionPath, path, params, r.use); match {
 			// Get params from the path detectionPath
@@ -98,10 +112,7 @@ func (r *Route) match(detectionPath, path string, params *[maxParams]string) (ma
 
 func (app *App) next(req *Request, res *Response) (match bool, err error) {
 	// Get stack length
-	tree, ok := app.treeStack[req.Ctx().methodINT][req.Ctx().treePath]
-	if !ok {
-		tree = app.treeStack[req.Ctx().methodINT][""]
-	}
+	tree := req.Ctx().lookupTree(req.Ctx().methodINT)
 	lenr := len(tree) - 1
 
 	// Loop over the route stack starting from previous index
@@ -113,7 +124,7 @@ func (app *App) next(req *Request, res *Response) (match bool, err error) {
 		route := tree[req.Ctx().indexRoute]
 
 		// Check if it matches the request path
-		match = route.match(req.Ctx().detectionPath, req.Ctx().path, &req.Ctx().values)
+		match = req.Ctx().matchRoute(route)
 
 		// No match, next route
 		if !match {
@@ -122,9 +133,24 @@ func (app *App) next(req *Request, res *Response) (match bool, err error) {
 		// Pass route reference and param values
 		req.Ctx().route = route
 
+		// Use the config of the app which registered the route
+		if route.app != req.Ctx().app {
+			req.Ctx().app = route.app
+			// The body limit of the server can be higher than the one of the mounted app
+			if length := req.Ctx().fasthttp.Request.Header.ContentLength(); length > 0 && length > route.app.config.BodyLimit {
+				return match, ErrRequestEntityTooLarge
+			}
+		}
+
 		// Non use handler matched
 		if !req.Ctx().matched && !route.use {
 			req.Ctx().matched = true
+			// Redirect to the registered path if the route only matches after the normalization
+			if app.config.RedirectFixedPath && !route.star {
+				if location := req.Ctx().fixedPath(route); location != "" {
+					return match, req.Ctx().redirectFixedPath(location)
+				}
+			}
 		}
 
 		// Execute first handler of route
@@ -133,6 +159,14 @@ func (app *App) next(req *Request, res *Response) (match bool, err error) {
 		return match, err // Stop scanning the stack
 	}
 
+	// Not found responses are handled by the app which routes the request
+	req.ctx.app = req.ctx.router
+
+	// The path is registered, but not for the requested version
+	if !res.ctx.matched && res.ctx.versionMismatch {
+		return false, ErrNotAcceptable
+	}
+
 	// If c.Next() does not match, return 404
 	_ = res.Status(StatusNotFound)
 	_ = res.String("Cannot " + req.Method() + " " + req.ctx.pathOriginal)
@@ -140,6 +174,13 @@ func (app *App) next(req *Request, res *Response) (match bool, err error) {
 	// If no match, scan stack again if other methods match the request
 	// Moved from app.handler because middleware may break the route chain
 	if !res.ctx.matched && methodExist(req.ctx) {
+		// Answer OPTIONS requests with the allowed methods of the path
+		if app.config.AutoOptions && req.ctx.methodINT == app.methodInt(MethodOptions) {
+			req.ctx.Append(HeaderAllow, MethodOptions)
+			req.ctx.fasthttp.Response.ResetBody()
+			_ = res.Status(StatusNoContent)
+			return true, nil
+		}
 		err = ErrMethodNotAllowed
 	}
 	return
@@ -156,15 +197,25 @@ func (app *App) handler(rctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// Serve HEAD requests with the GET routes if no HEAD route matches
+	if get := app.methodInt(MethodGet); app.config.AutoHead && get != -1 && req.ctx.methodINT == app.methodInt(MethodHead) {
+		if !methodMatch(req.ctx, req.ctx.methodINT) && methodMatch(req.ctx, get) {
+			req.ctx.methodINT = get
+			req.ctx.fasthttp.Response.SkipBody = true
+		}
+		// Reset stack index
+		req.ctx.indexRoute = -1
+	}
+
 	// Find match in stack
 	match, err := app.next(req, res)
 	if err != nil {
-		if catch := req.ctx.app.ErrorHandler(req, res, err); catch != nil {
+		if catch := app.ErrorHandler(req, res, err); catch != nil {
 			_ = res.Status(StatusInternalServerError).Send()
 		}
 	}
 	// Generate ETag if enabled
-	if match && app.config.ETag {
+	if match && req.ctx.app.config.ETag {
 		setETag(req.ctx, false)
 	}
 	// Release Ctx
@@ -176,7 +227,7 @@ func (app *App) addPrefixToRoute(prefix string, route *Route) *Route {
 	prettyPath := prefixedPath
 	// Case sensitive routing, all to lowercase
 	if !app.config.CaseSensitive {
-		prettyPath = utils.ToLower(prettyPath)
+		prettyPath = toLowerRoute(prettyPath)
 	}
 	// Strict routing, remove trailing slashes
 	if !app.config.StrictRouting && len(prettyPath) > 1 {
@@ -186,6 +237,7 @@ func (app *App) addPrefixToRoute(prefix string, route *Route) *Route {
 	route.Path = prefixedPath
 	route.path = RemoveEscapeChar(prettyPath)
 	route.routeParser = parseRoute(prettyPath)
+	route.rawParser = parseRoute(prefixedPath)
 	route.root = false
 	route.star = false
 
@@ -202,20 +254,36 @@ func (app *App) copyRoute(route *Route) *Route {
 		// Path data
 		path:        route.path,
 		routeParser: route.routeParser,
+		rawParser:   route.rawParser,
+		host:        route.host,
+		app:         route.app,
 		Params:      route.Params,
 
 		// Public data
+		Name:     route.Name,
 		Path:     route.Path,
+		Host:     route.Host,
+		Version:  route.Version,
+		Meta:     route.Meta,
 		Method:   route.Method,
 		Handlers: route.Handlers,
 	}
 }
 
-func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
+func (app *App) register(method, pathRaw string, grp *Group, handlers ...Handler) Router {
+	if err := app.registerRoute(method, pathRaw, grp, handlers...); err != nil {
+		panic(err.Error() + "\n")
+	}
+	return app
+}
+
+// registerRoute creates the route and adds it to the stack, it returns the conflicts of the route stack
+// if the server is already running and the conflict policy is RouteConflictPanic
+func (app *App) registerRoute(method, pathRaw string, grp *Group, handlers ...Handler) error {
 	// Uppercase HTTP methods
 	method = utils.ToUpper(method)
 	// Check if the HTTP method is valid unless it's USE
-	if method != methodUse && methodInt(method) == -1 {
+	if method != methodUse && app.methodInt(method) == -1 {
 		panic(fmt.Sprintf("add: invalid http method %s\n", method))
 	}
 	// A route requires atleast one ctx handler
@@ -234,7 +302,7 @@ func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
 	pathPretty := pathRaw
 	// Case sensitive routing, all to lowercase
 	if !app.config.CaseSensitive {
-		pathPretty = utils.ToLower(pathPretty)
+		pathPretty = toLowerRoute(pathPretty)
 	}
 	// Strict routing, remove trailing slashes
 	if !app.config.StrictRouting && len(pathPretty) > 1 {
@@ -260,6 +328,8 @@ func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
 		// Path data
 		path:        RemoveEscapeChar(pathPretty),
 		routeParser: parsedPretty,
+		rawParser:   parsedRaw,
+		app:         app,
 		Params:      parsedRaw.params,
 
 		// Public data
@@ -267,25 +337,23 @@ func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
 		Method:   method,
 		Handlers: handlers,
 	}
-	// Increment global handler count
-	atomic.AddUint32(&app.handlersCount, uint32(len(handlers)))
+	// Restrict the route to the host and the version of the group
+	route.withGroup(grp)
 
+	methods := []string{method}
 	// Middleware route matches all HTTP methods
 	if isUse {
-		// Add route to all HTTP methods stack
-		for _, m := range intMethod {
-			// Create a route copy to avoid duplicates during compression
-			r := route
-			app.addRoute(m, &r)
-		}
-	} else {
-		// Add route to stack
-		app.addRoute(method, &route)
+		methods = app.config.RequestMethods
 	}
-	return app
+	if err := app.addRoute(&route, methods...); err != nil {
+		return err
+	}
+	// Increment global handler count
+	atomic.AddUint32(&app.handlersCount, uint32(len(handlers)))
+	return nil
 }
 
-func (app *App) registerStatic(prefix, root string, config ...Static) Router {
+func (app *App) registerStatic(prefix, root string, grp *Group, config ...Static) Router {
 	// For security we want to restrict to the current work directory.
 	if root == "" {
 		root = "."
@@ -399,41 +467,87 @@ func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 		use:  true,
 		root: isRoot,
 		path: prefix,
+		app:  app,
 		// Public data
 		Method:   MethodGet,
 		Path:     prefix,
 		Handlers: []Handler{handler},
 	}
+	// Restrict the route to the host and the version of the group
+	route.withGroup(grp)
+	// Add route to the GET and the HEAD stack
+	app.mustAddRoute(&route, MethodGet, MethodHead)
 	// Increment global handler count
 	atomic.AddUint32(&app.handlersCount, 1)
-	// Add route to stack
-	app.addRoute(MethodGet, &route)
-	// Add HEAD route
-	app.addRoute(MethodHead, &route)
 	return app
 }
 
-func (app *App) addRoute(method string, route *Route) {
-	// Get unique HTTP method identifier
-	m := methodInt(method)
-
-	// prevent identically route registration
-	l := len(app.stack[m])
-	if l > 0 && app.stack[m][l-1].Path == route.Path && route.use == app.stack[m][l-1].use {
-		preRoute := app.stack[m][l-1]
-		preRoute.Handlers = append(preRoute.Handlers, route.Handlers...)
+// addRoute adds the route to the stacks of the methods, every method gets its own copy of the route if
+// there are several. If the server is already running, the routes are published at once and a conflict
+// of the route stack is returned before the stack is changed.
+func (app *App) addRoute(route *Route, methods ...string) error {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	// Running requests could still use the stack, so it's copied once the tree is built
+	stack := app.stack
+	if app.treeBuilt {
+		stack = make([][]*Route, len(app.stack))
+		copy(stack, app.stack)
+	}
+	added, latest := 0, route
+	for _, method := range methods {
+		// Get unique HTTP method identifier
+		m := app.methodInt(method)
+		r := route
+		if len(methods) > 1 {
+			// Create a route copy to avoid duplicates during compression
+			copied := *route
+			r = &copied
+		}
+		routes := stack[m]
+		l := len(routes)
+		if app.treeBuilt {
+			routes = routes[:l:l]
+		}
+		// prevent identically route registration
+		if l > 0 && routes[l-1].Path == r.Path && r.use == routes[l-1].use && r.Host == routes[l-1].Host && r.Version == routes[l-1].Version {
+			// Copy the previous route, because running requests could still use it
+			preRoute := *routes[l-1]
+			preRoute.Handlers = append(preRoute.Handlers[:len(preRoute.Handlers):len(preRoute.Handlers)], r.Handlers...)
+			routes = append(routes[:l-1], &preRoute)
+		} else {
+			// Increment global route position
+			r.pos = atomic.AddUint32(&app.routesPos, 1)
+			r.Method = method
+			// Add route to the stack
+			routes = append(routes, r)
+			added++
+		}
+		stack[m] = routes
+		latest = r
+	}
+	// Publish the routes at once if the server is already running
+	if app.treeBuilt {
+		if err := app.publishStack(stack); err != nil {
+			return err
+		}
 	} else {
-		// Increment global route position
-		route.pos = atomic.AddUint32(&app.routesCount, 1)
-		route.Method = method
-		// Add route to the stack
-		app.stack[m] = append(app.stack[m], route)
 		app.routesRefreshed = true
 	}
+	atomic.AddUint32(&app.routesCount, uint32(added))
 
 	latestRoute.mu.Lock()
-	latestRoute.route = route
+	latestRoute.route = latest
 	latestRoute.mu.Unlock()
+	return nil
+}
+
+// mustAddRoute adds the route like addRoute and panics if the route stack has conflicts
+func (app *App) mustAddRoute(route *Route, methods ...string) {
+	if err := app.addRoute(route, methods...); err != nil {
+		panic(err.Error() + "\n")
+	}
 }
 
 // buildTree build the prefix tree from the previously registered routes
@@ -441,32 +555,32 @@ func (app *App) buildTree() *App {
 	if !app.routesRefreshed {
 		return app
 	}
-	// loop all the methods and stacks and create the prefix tree
-	for m := range intMethod {
-		app.treeStack[m] = make(map[string][]*Route)
-		for _, route := range app.stack[m] {
-			treePath := ""
-			if len(route.routeParser.segs) > 0 && len(route.routeParser.segs[0].Const) >= 3 {
-				treePath = route.routeParser.segs[0].Const[:3]
-			}
-			// create tree stack
-			app.treeStack[m][treePath] = append(app.treeStack[m][treePath], route)
-		}
+	if err := app.publishStack(app.stack); err != nil {
+		panic(err.Error() + "\n")
 	}
-	// loop the methods and tree stacks and add global stack and sort everything
-	for m := range intMethod {
-		for treePart := range app.treeStack[m] {
-			if treePart != "" {
-				// merge global tree routes in current tree stack
-				app.treeStack[m][treePart] = uniqueRouteStack(append(app.treeStack[m][treePart], app.treeStack[m][""]...))
-			}
-			// sort tree slices with the positions
-			sort.Slice(app.treeStack[m][treePart], func(i, j int) bool {
-				return app.treeStack[m][treePart][i].pos < app.treeStack[m][treePart][j].pos
-			})
+	return app
+}
+
+// publishStack builds the radix trees of the stack and replaces the stack and the trees at once,
+// the stack isn't used if it has conflicts and the conflict policy is RouteConflictPanic
+func (app *App) publishStack(stack [][]*Route) error {
+	// loop all the methods and stacks and create the radix tree,
+	// the stacks are already ordered by the positions of the routes
+	treeStack := make([]*routeTree, len(stack))
+	for m := range stack {
+		treeStack[m] = newRouteTree(stack[m])
+	}
+	// report the conflicts of the routes before the trees are used
+	if app.config.RouteConflictPolicy != RouteConflictIgnore {
+		conflicts := app.analyseRoutes(stack, treeStack)
+		if app.config.RouteConflictPolicy == RouteConflictPanic && len(conflicts) > 0 {
+			return fmt.Errorf("route: conflicts in the route stack\n%s", strings.Join(conflicts, "\n"))
 		}
+		app.routeConflicts = conflicts
 	}
+	// replace the trees at once, running requests keep the trees they started with
+	app.stack = stack
+	app.treeStack.Store(treeStack)
 	app.routesRefreshed = false
-
-	return app
+	return nil
 }
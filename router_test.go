@@ -145,6 +145,73 @@ func Test_Route_Match_Parser(t *testing.T) {
 	utils.AssertEqual(t, "test", app.getString(body))
 }
 
+func Test_Route_Match_Constraints(t *testing.T) {
+	app := New()
+
+	app.Get("/users/:id<int>", func(req *Request, res *Response) error {
+		return res.String("id " + req.Param("id"))
+	})
+	app.Get("/users/:name", func(req *Request, res *Response) error {
+		return res.String("name " + req.Param("name"))
+	})
+	app.Get("/tags/:tag<regex(^[A-Z]+$)>", func(req *Request, res *Response) error {
+		return res.String(req.Param("tag"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/users/42", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "id 42", app.getString(body))
+
+	// constraint not fulfilled, falls through to the next route
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/users/john", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "name john", app.getString(body))
+
+	// the regular expression is not lowercased by the case-insensitive routing
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/tags/GO", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/tags/go", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+}
+
+func Test_Route_Match_OptionalGroups(t *testing.T) {
+	app := New()
+
+	app.Get("/docs(/:lang)?/intro", func(req *Request, res *Response) error {
+		return res.String("lang " + req.Param("lang", "default"))
+	})
+
+	utils.AssertEqual(t, []string{"lang"}, app.Stack()[methodInt(MethodGet)][0].Params)
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/docs/de/intro", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "lang de", app.getString(body))
+
+	// missing group
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/docs/intro", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "lang default", app.getString(body))
+}
+
 func Test_Route_Match_Middleware(t *testing.T) {
 	app := New()
 
@@ -279,7 +346,7 @@ func Test_Router_Register_Missing_Handler(t *testing.T) {
 			utils.AssertEqual(t, "missing handler in route: /doe\n", fmt.Sprintf("%v", err))
 		}
 	}()
-	app.register("USE", "/doe")
+	app.register("USE", "/doe", nil)
 }
 
 func Test_Ensure_Router_Interface_Implementation(t *testing.T) {
@@ -558,6 +625,87 @@ func Benchmark_Router_Chain(b *testing.B) {
 	}
 }
 
+// go test -run Test_Router_Handler_Allocs
+func Test_Router_Handler_Allocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates in the handler")
+	}
+	app := New()
+	app.Use(func(req *Request, res *Response) error {
+		res.Header.Set(HeaderXContentTypeOptions, "nosniff")
+		return req.Next()
+	})
+	app.Get("/user/:id", func(req *Request, res *Response) error {
+		_ = req.Header.Get(HeaderAccept)
+		return res.String("Hello, World!")
+	})
+	appHandler := app.Handler()
+
+	c := &fasthttp.RequestCtx{}
+	c.Request.Header.SetMethod(MethodGet)
+	c.Request.Header.Set(HeaderAccept, MIMETextPlain)
+	c.URI().SetPath("/user/1337")
+
+	// warm up the pools
+	appHandler(c)
+	allocs := testing.AllocsPerRun(100, func() {
+		appHandler(c)
+	})
+	utils.AssertEqual(t, float64(0), allocs)
+	utils.AssertEqual(t, "Hello, World!", string(c.Response.Body()))
+}
+
+// go test -run Test_Router_Pooled_ReqRes
+func Test_Router_Pooled_ReqRes(t *testing.T) {
+	app := New()
+	var first *Response
+	app.Use(func(req *Request, res *Response) error {
+		first = res
+		if req.Query("status") != "" {
+			res.Status(StatusAccepted)
+		}
+		return req.Next()
+	})
+	app.Get("/", func(req *Request, res *Response) error {
+		// the same request and response are passed through the stack
+		utils.AssertEqual(t, true, first == res)
+		utils.AssertEqual(t, true, req.ctx.req == req)
+		return res.Send()
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/?status=1", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusAccepted, resp.StatusCode)
+
+	// the state of the response is reset for the next request
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "", string(body))
+}
+
+// go test -v ./... -run=^$ -bench=Benchmark_Router_String -benchmem -count=4
+func Benchmark_Router_String(b *testing.B) {
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		return res.String("Hello, World!")
+	})
+	appHandler := app.Handler()
+
+	c := &fasthttp.RequestCtx{}
+	c.Request.Header.SetMethod(MethodGet)
+	c.URI().SetPath("/")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		appHandler(c)
+	}
+	utils.AssertEqual(b, "Hello, World!", string(c.Response.Body()))
+}
+
 // go test -v ./... -run=^$ -bench=Benchmark_Router_WithCompression -benchmem -count=4
 func Benchmark_Router_WithCompression(b *testing.B) {
 	app := New()
@@ -605,7 +753,9 @@ func Benchmark_Router_Next(b *testing.B) {
 	}
 	utils.AssertEqual(b, nil, err)
 	utils.AssertEqual(b, true, res)
-	utils.AssertEqual(b, 4, req.ctx.indexRoute)
+	// indexRoute points into the routes of the tree node for the path, not into the whole stack,
+	// "/user/keys/:id" is the only DELETE route of the node
+	utils.AssertEqual(b, 0, req.ctx.indexRoute)
 }
 
 // go test -v ./... -run=^$ -bench=Benchmark_Route_Match -benchmem -count=4
@@ -794,3 +944,250 @@ type routeJSON struct {
 	TestRoutes []testRoute `json:"testRoutes"`
 	GithubAPI  []testRoute `json:"githubAPI"`
 }
+
+// go test -run Test_Router_Tree_Lookup
+func Test_Router_Tree_Lookup(t *testing.T) {
+	t.Parallel()
+	var routes []*Route
+	add := func(path string, use bool) {
+		route := &Route{
+			pos:         uint32(len(routes) + 1),
+			use:         use,
+			root:        use && path == "/",
+			star:        path == "*",
+			path:        path,
+			Path:        path,
+			routeParser: parseRoute(path),
+		}
+		route.Params = route.routeParser.params
+		routes = append(routes, route)
+	}
+	add("/", true)
+	add("/api", true)
+	add("/api/users", false)
+	add("/api/users/:id", false)
+	add("/api/user", false)
+	add("/:param", false)
+	add("/api/orders(/:id)?", false)
+	add("*", false)
+
+	tree := newRouteTree(routes)
+	lookup := func(path string) (paths []string) {
+		for _, route := range tree.lookup(path, nil) {
+			paths = append(paths, route.Path)
+		}
+		return
+	}
+
+	utils.AssertEqual(t, []string{"/", "/api", "/api/users", "/api/users/:id", "/:param", "*"}, lookup("/api/users"))
+	utils.AssertEqual(t, []string{"/", "/api", "/api/users/:id", "/:param", "*"}, lookup("/api/users/1"))
+	utils.AssertEqual(t, []string{"/", "/api", "/api/user", "/:param", "*"}, lookup("/api/user"))
+	utils.AssertEqual(t, []string{"/", "/api", "/:param", "/api/orders(/:id)?", "*"}, lookup("/api/orders/1"))
+	utils.AssertEqual(t, []string{"/", "/:param", "*"}, lookup("/contact"))
+	utils.AssertEqual(t, []string{"/", "/:param", "*"}, lookup(""))
+
+	var nilTree *routeTree
+	utils.AssertEqual(t, 0, len(nilTree.lookup("/api", nil)))
+}
+
+// go test -run Test_Router_Tree_Order
+func Test_Router_Tree_Order(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	var order []string
+	handler := func(name string) Handler {
+		return func(req *Request, res *Response) error {
+			order = append(order, name)
+			return req.Next()
+		}
+	}
+	app.Use(handler("use /"))
+	app.Get("/api/:id", handler("get /api/:id"))
+	app.Use("/api", handler("use /api"))
+	app.Get("/api/users", handler("get /api/users"))
+	app.Get("/:section/users", handler("get /:section/users"))
+	app.Get("/api/users", func(req *Request, res *Response) error {
+		order = append(order, "end")
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/api/users", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, []string{"use /", "get /api/:id", "use /api", "get /api/users", "get /:section/users", "end"}, order)
+}
+
+// registerBenchmarkRoutes registers a large api with static and parameterised routes
+func registerBenchmarkRoutes(app *App) {
+	h := func(req *Request, res *Response) error {
+		return nil
+	}
+	app.Use(h)
+	for i := 0; i < 300; i++ {
+		prefix := fmt.Sprintf("/service%d", i)
+		app.Use(prefix, h)
+		app.Get(prefix+"/users", h)
+		app.Get(prefix+"/users/:id", h)
+		app.Get(prefix+"/users/:id/posts/:post", h)
+		app.Get(prefix+"/orders/:id<int>", h)
+	}
+}
+
+// legacyTreeStack divides the routes into buckets of the first three characters like the previous router
+func legacyTreeStack(routes []*Route) map[string][]*Route {
+	buckets := make(map[string][]*Route)
+	for _, route := range routes {
+		treePath := ""
+		if len(route.routeParser.segs) > 0 && len(route.routeParser.segs[0].Const) >= 3 {
+			treePath = route.routeParser.segs[0].Const[:3]
+		}
+		buckets[treePath] = append(buckets[treePath], route)
+	}
+	for treePart := range buckets {
+		if treePart != "" {
+			buckets[treePart] = mergeRoutes(append([]*Route(nil), buckets[""]...), buckets[treePart])
+		}
+	}
+	return buckets
+}
+
+// go test -v -run=^$ -bench=Benchmark_Router_Tree -benchmem -count=4
+func Benchmark_Router_Tree(b *testing.B) {
+	app := New()
+	registerBenchmarkRoutes(app)
+	app.startupProcess()
+
+	paths := []string{"/service0/users", "/service150/users/1337", "/service299/users/1337/posts/42", "/service299/orders/42"}
+	routes := app.stack[methodInt(MethodGet)]
+	var params [maxParams]string
+
+	b.Run("legacy", func(b *testing.B) {
+		buckets := legacyTreeStack(routes)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			path := paths[n%len(paths)]
+			for _, route := range buckets[path[:3]] {
+				if !route.use && route.match(path, path, &params) {
+					break
+				}
+			}
+		}
+	})
+
+	b.Run("radix", func(b *testing.B) {
+		tree := app.treeStack.Load().([]*routeTree)[methodInt(MethodGet)]
+		candidates := make([]*Route, 0, 16)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			path := paths[n%len(paths)]
+			candidates = tree.lookup(path, candidates[:0])
+			for _, route := range candidates {
+				if !route.use && route.match(path, path, &params) {
+					break
+				}
+			}
+		}
+	})
+}
+
+// go test -v -run=^$ -bench=Benchmark_Router_Tree_Next -benchmem -count=4
+func Benchmark_Router_Tree_Next(b *testing.B) {
+	app := New()
+	registerBenchmarkRoutes(app)
+	app.startupProcess()
+
+	request := &fasthttp.RequestCtx{}
+	request.Request.Header.SetMethod(MethodGet)
+	request.URI().SetPath("/service299/users/1337/posts/42")
+	var res bool
+	var err error
+
+	req, resp := app.AcquireReqRes(request)
+	defer app.ReleaseCtx(req.ctx)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		req.ctx.indexRoute = -1
+		res, err = app.next(req, resp)
+	}
+	utils.AssertEqual(b, nil, err)
+	utils.AssertEqual(b, true, res)
+}
+
+// go test -run Test_Router_RedirectFixedPath
+func Test_Router_RedirectFixedPath(t *testing.T) {
+	t.Parallel()
+	app := New(Config{RedirectFixedPath: true})
+
+	var calls int
+	app.Use(func(req *Request, res *Response) error {
+		calls++
+		return req.Next()
+	})
+	app.Get("/Users/:id", testEmptyHandler)
+	app.Post("/Users/:id", testEmptyHandler)
+	app.Get("/About", testEmptyHandler)
+	app.Get("/:name", testEmptyHandler)
+	app.Get("/docs(/:lang)?/Intro", testEmptyHandler)
+	app.Get("/files/:name?", testEmptyHandler)
+
+	testCases := []struct {
+		method   string
+		url      string
+		code     int
+		location string
+	}{
+		{method: MethodGet, url: "/Users/5", code: StatusOK},
+		{method: MethodGet, url: "/users/5?page=1", code: StatusMovedPermanently, location: "/Users/5?page=1"},
+		{method: MethodHead, url: "/USERS/Ab", code: StatusMovedPermanently, location: "/Users/Ab"},
+		{method: MethodGet, url: "/Users/5/", code: StatusMovedPermanently, location: "/Users/5"},
+		{method: MethodPost, url: "/users/5", code: StatusPermanentRedirect, location: "/Users/5"},
+		{method: MethodGet, url: "/about", code: StatusMovedPermanently, location: "/About"},
+		{method: MethodGet, url: "/About/", code: StatusMovedPermanently, location: "/About"},
+		{method: MethodGet, url: "/Contact", code: StatusOK},
+		{method: MethodGet, url: "/Contact/", code: StatusMovedPermanently, location: "/Contact"},
+		{method: MethodGet, url: "/docs/de/intro", code: StatusMovedPermanently, location: "/docs/de/Intro"},
+		{method: MethodGet, url: "/DOCS/intro", code: StatusMovedPermanently, location: "/docs/Intro"},
+		{method: MethodGet, url: "/docs/Intro", code: StatusOK},
+		{method: MethodGet, url: "/files/a", code: StatusOK},
+		{method: MethodGet, url: "/Files/A", code: StatusMovedPermanently, location: "/files/A"},
+	}
+	for _, tc := range testCases {
+		resp, err := app.Test(httptest.NewRequest(tc.method, tc.url, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, tc.code, resp.StatusCode, tc.method+" "+tc.url)
+		utils.AssertEqual(t, tc.location, resp.Header.Get(HeaderLocation), tc.method+" "+tc.url)
+	}
+	utils.AssertEqual(t, len(testCases), calls)
+
+	// The unescaped values are escaped again for the location
+	app = New(Config{RedirectFixedPath: true, UnescapePath: true})
+	app.Get("/Users/:id", testEmptyHandler)
+	app.Get("/Files/*", testEmptyHandler)
+	for url, location := range map[string]string{
+		"/users/a%3Fb":         "/Users/a%3Fb",
+		"/USERS/a%23b%25c%20d": "/Users/a%23b%25c%20d",
+		"/Users/a%3Fb/?x=1":    "/Users/a%3Fb?x=1",
+		"/files/a%20b/c%3F":    "/Files/a%20b/c%3F",
+	} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, url, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusMovedPermanently, resp.StatusCode, url)
+		utils.AssertEqual(t, location, resp.Header.Get(HeaderLocation), url)
+	}
+	// The escaped form of a registered path isn't redirected again
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/Users/a%20b", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	// Without the config the normalized paths are served directly
+	app = New()
+	app.Get("/Users/:id", testEmptyHandler)
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/users/5/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+}
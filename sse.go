@@ -0,0 +1,238 @@
+package lightning
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// DefaultSSEKeepAlive is the interval of the keep-alive comments of res.SSE
+const DefaultSSEKeepAlive = 15 * time.Second
+
+// ErrSSEClosed is returned by the SSEStream if the client disconnected or the stream has ended
+var ErrSSEClosed = errors.New("sse: stream is closed")
+
+// SSEEvent is a single Server-Sent Event
+type SSEEvent struct {
+	ID    string        // Event ID, the client sends it as Last-Event-ID on reconnect
+	Event string        // Event type, "message" if empty
+	Data  interface{}   // Strings and bytes are sent as they are, other values are encoded as JSON
+	Retry time.Duration // Reconnection time of the client
+}
+
+// SSEStream writes Server-Sent Events to the client, it's safe for concurrent use
+type SSEStream struct {
+	mu          sync.Mutex
+	w           *bufio.Writer
+	encoder     utils.JSONMarshal
+	lastEventID string
+	closed      bool
+	done        chan struct{}
+}
+
+// SSE streams Server-Sent Events to the client, the stream ends when fn returns or the client disconnects.
+// A keep-alive comment is sent on the interval, which defaults to DefaultSSEKeepAlive and is disabled with 0.
+// The handler returns before the events are streamed, so fn must not use the request or the response.
+//  return res.SSE(func(stream *lightning.SSEStream) error {
+//  	for update := range updates {
+//  		if err := stream.Send(lightning.SSEEvent{Event: "update", Data: update}); err != nil {
+//  			return err
+//  		}
+//  	}
+//  	return nil
+//  })
+func (res *Response) SSE(fn func(stream *SSEStream) error, keepAlive ...time.Duration) error {
+	interval := DefaultSSEKeepAlive
+	if len(keepAlive) > 0 {
+		interval = keepAlive[0]
+	}
+	// The ctx is released before the events are streamed
+	lastEventID := utils.CopyString(res.ctx.Get(HeaderLastEventID))
+	encoder := res.ctx.app.config.JSONEncoder
+
+	res.ctx.fasthttp.Response.Header.SetContentType("text/event-stream")
+	res.ctx.fasthttp.Response.Header.Set(HeaderCacheControl, "no-cache")
+	res.ctx.fasthttp.Response.Header.Set(HeaderConnection, "keep-alive")
+	res.ctx.fasthttp.Response.Header.Set("X-Accel-Buffering", "no")
+	res.rType = "stream"
+
+	res.ctx.fasthttp.SetBodyStreamWriter(func(w *bufio.Writer) {
+		stream := newSSEStream(w, encoder, lastEventID)
+		if interval > 0 {
+			go stream.keepAlive(interval)
+		}
+		_ = fn(stream)
+		stream.close()
+	})
+	return nil
+}
+
+// newSSEStream creates a stream which writes to w
+func newSSEStream(w *bufio.Writer, encoder utils.JSONMarshal, lastEventID string) *SSEStream {
+	return &SSEStream{
+		w:           w,
+		encoder:     encoder,
+		lastEventID: lastEventID,
+		done:        make(chan struct{}),
+	}
+}
+
+// LastEventID returns the Last-Event-ID header of a reconnecting client
+func (s *SSEStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done is closed when the client disconnected or the stream has ended
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Send writes the event and flushes it to the client
+func (s *SSEStream) Send(event SSEEvent) error {
+	var data string
+	switch v := event.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		raw, err := s.encoder(v)
+		if err != nil {
+			return err
+		}
+		data = string(raw)
+	}
+
+	var sb strings.Builder
+	if event.ID != "" {
+		sb.WriteString("id: " + removeNewLines(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		sb.WriteString("event: " + removeNewLines(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	// Every line of the data is a data field
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteByte('\n')
+	return s.write(sb.String())
+}
+
+// Comment writes a comment which is ignored by the client
+func (s *SSEStream) Comment(comment string) error {
+	return s.write(": " + removeNewLines(comment) + "\n\n")
+}
+
+// write writes and flushes the message, the stream is closed if the client disconnected
+func (s *SSEStream) write(message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSSEClosed
+	}
+	if _, err := s.w.WriteString(message); err != nil {
+		s.closeLocked()
+		return ErrSSEClosed
+	}
+	if err := s.w.Flush(); err != nil {
+		s.closeLocked()
+		return ErrSSEClosed
+	}
+	return nil
+}
+
+// keepAlive sends comments on the interval until the stream is closed
+func (s *SSEStream) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if s.Comment("keep-alive") != nil {
+				return
+			}
+		}
+	}
+}
+
+// close ends the stream
+func (s *SSEStream) close() {
+	s.mu.Lock()
+	s.closeLocked()
+	s.mu.Unlock()
+}
+
+func (s *SSEStream) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+// removeNewLines replaces the line breaks of single line fields
+func removeNewLines(s string) string {
+	if strings.ContainsAny(s, "\r\n") {
+		return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	}
+	return s
+}
+
+// ReadSSE reads the events of a Server-Sent Events stream, e.g. of a response of app.Test.
+// Comments are skipped and the data of the events is returned as string.
+//  resp, _ := app.Test(httptest.NewRequest("GET", "/events", nil))
+//  events, err := lightning.ReadSSE(resp.Body)
+func ReadSSE(r io.Reader) ([]SSEEvent, error) {
+	var events []SSEEvent
+	var event SSEEvent
+	var data []string
+	hasFields := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// An empty line dispatches the event
+		if line == "" {
+			if hasFields {
+				if data != nil {
+					event.Data = strings.Join(data, "\n")
+				}
+				events = append(events, event)
+			}
+			event, data, hasFields = SSEEvent{}, nil, false
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i != -1 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		hasFields = true
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				event.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,105 @@
+package lightning
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// go test -run Test_Response_SSE
+func Test_Response_SSE(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/events", func(req *Request, res *Response) error {
+		return res.SSE(func(stream *SSEStream) error {
+			start := 0
+			if id := stream.LastEventID(); id != "" {
+				start = 2
+			}
+			for i := start; i < 3; i++ {
+				if err := stream.Send(SSEEvent{ID: strconv.Itoa(i + 1), Data: "tick"}); err != nil {
+					return err
+				}
+			}
+			_ = stream.Send(SSEEvent{Event: "user", Data: Map{"name": "john"}, Retry: 3 * time.Second})
+			return stream.Send(SSEEvent{Event: "multi\nline", Data: "first\nsecond"})
+		})
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/events", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "text/event-stream", resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, "no-cache", resp.Header.Get(HeaderCacheControl))
+
+	events, err := ReadSSE(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, []SSEEvent{
+		{ID: "1", Data: "tick"},
+		{ID: "2", Data: "tick"},
+		{ID: "3", Data: "tick"},
+		{Event: "user", Data: `{"name":"john"}`, Retry: 3 * time.Second},
+		{Event: "multiline", Data: "first\nsecond"},
+	}, events)
+
+	// Reconnecting clients continue after the last event
+	req := httptest.NewRequest(MethodGet, "/events", nil)
+	req.Header.Set(HeaderLastEventID, "2")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	events, err = ReadSSE(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 3, len(events))
+	utils.AssertEqual(t, "3", events[0].ID)
+}
+
+// go test -run Test_Response_SSE_KeepAlive
+func Test_Response_SSE_KeepAlive(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(req *Request, res *Response) error {
+		return res.SSE(func(stream *SSEStream) error {
+			time.Sleep(100 * time.Millisecond)
+			return stream.Send(SSEEvent{Data: "done"})
+		}, 20*time.Millisecond)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, strings.HasPrefix(string(body), ": keep-alive\n\n"))
+	utils.AssertEqual(t, true, strings.HasSuffix(string(body), "data: done\n\n"))
+
+	events, err := ReadSSE(bytes.NewReader(body))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, []SSEEvent{{Data: "done"}}, events)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+// go test -run Test_SSEStream_Disconnect
+func Test_SSEStream_Disconnect(t *testing.T) {
+	t.Parallel()
+	stream := newSSEStream(bufio.NewWriter(failingWriter{}), nil, "")
+	utils.AssertEqual(t, ErrSSEClosed, stream.Send(SSEEvent{Data: "lost"}))
+
+	select {
+	case <-stream.Done():
+	default:
+		t.Fatal("stream must be closed after the client disconnected")
+	}
+	utils.AssertEqual(t, ErrSSEClosed, stream.Comment("keep-alive"))
+}
@@ -0,0 +1,163 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package lightning
+
+import (
+	"github.com/ikidev/lightning/utils"
+)
+
+// routeTree is a compressed radix tree which divides the routes of a HTTP method by their static prefixes,
+// so that only the routes whose prefix is part of the request path have to be checked.
+type routeTree struct {
+	root routeNode
+}
+
+// routeNode is a node of the radix tree
+type routeNode struct {
+	prefix   string       // compressed part of the path which leads to this node
+	indices  []byte       // first characters of the children prefixes, used for the fast child search
+	children []*routeNode // child nodes
+	routes   []*Route     // routes which can match every path starting with the path of the node
+	exact    []*Route     // routes which only match the exact path of the node
+}
+
+// newRouteTree creates the radix tree for the given routes, the routes must be ordered by their positions
+func newRouteTree(routes []*Route) *routeTree {
+	tree := &routeTree{}
+	for _, route := range routes {
+		key, exact := route.treeKey()
+		tree.root.insert(key, route, exact)
+	}
+	return tree
+}
+
+// treeKey returns the static prefix of the route which is used as key in the radix tree and
+// whether the route can only match this exact path
+func (r *Route) treeKey() (key string, exact bool) {
+	// global middlewares and wildcards match every path
+	if r.star || (r.use && r.root) {
+		return "", false
+	}
+	// routes without parameters match the prettified path, middlewares also its sub paths
+	if len(r.Params) == 0 && len(r.routeParser.variants) == 0 {
+		return r.path, !r.use
+	}
+	// routes with optional groups match the common prefix of all their variants
+	if len(r.routeParser.variants) > 0 {
+		key = constPrefix(r.routeParser.variants[0].parser.segs)
+		for _, variant := range r.routeParser.variants[1:] {
+			key = key[:commonPrefixLength(key, constPrefix(variant.parser.segs))]
+		}
+		return key, false
+	}
+	// routes with parameters match all paths which start with the first constant part
+	return constPrefix(r.routeParser.segs), false
+}
+
+// constPrefix returns the constant part at the beginning of the segments
+func constPrefix(segs []*routeSegment) string {
+	if len(segs) == 0 || segs[0].IsParam {
+		return ""
+	}
+	// the trailing slash can be optional, e.g. "/api/:param?" matches "/api"
+	return utils.TrimRight(segs[0].Const, '/')
+}
+
+// insert adds the route under the given key to the tree
+func (n *routeNode) insert(key string, route *Route, exact bool) {
+	node := n
+	for len(key) > 0 {
+		child := node.child(key[0])
+		// no matching child, create a new leaf with the rest of the key
+		if child == nil {
+			child = &routeNode{prefix: key}
+			node.indices = append(node.indices, key[0])
+			node.children = append(node.children, child)
+			node = child
+			break
+		}
+		// split the child if the key only shares a part of its prefix
+		common := commonPrefixLength(key, child.prefix)
+		if common < len(child.prefix) {
+			split := &routeNode{
+				prefix:   child.prefix[common:],
+				indices:  child.indices,
+				children: child.children,
+				routes:   child.routes,
+				exact:    child.exact,
+			}
+			child.prefix = child.prefix[:common]
+			child.indices = []byte{split.prefix[0]}
+			child.children = []*routeNode{split}
+			child.routes = nil
+			child.exact = nil
+		}
+		key = key[common:]
+		node = child
+	}
+	if exact {
+		node.exact = append(node.exact, route)
+	} else {
+		node.routes = append(node.routes, route)
+	}
+}
+
+// child returns the child whose prefix starts with the given character
+func (n *routeNode) child(c byte) *routeNode {
+	for i := range n.indices {
+		if n.indices[i] == c {
+			return n.children[i]
+		}
+	}
+	return nil
+}
+
+// lookup appends all routes which can match the path to the given slice,
+// the routes are ordered by their positions to keep the registration order
+func (t *routeTree) lookup(path string, routes []*Route) []*Route {
+	if t == nil {
+		return routes
+	}
+	node := &t.root
+	routes = mergeRoutes(routes, node.routes)
+	if len(path) == 0 {
+		return mergeRoutes(routes, node.exact)
+	}
+	for len(path) > 0 {
+		node = node.child(path[0])
+		if node == nil || len(path) < len(node.prefix) || path[:len(node.prefix)] != node.prefix {
+			break
+		}
+		path = path[len(node.prefix):]
+		routes = mergeRoutes(routes, node.routes)
+		if len(path) == 0 {
+			routes = mergeRoutes(routes, node.exact)
+		}
+	}
+	return routes
+}
+
+// mergeRoutes adds the routes to the ordered slice and keeps the order of the positions,
+// both slices are already ordered, so the insertion sort only moves a few entries
+func mergeRoutes(dst, src []*Route) []*Route {
+	for _, route := range src {
+		dst = append(dst, route)
+		i := len(dst) - 1
+		for ; i > 0 && dst[i-1].pos > route.pos; i-- {
+			dst[i] = dst[i-1]
+		}
+		dst[i] = route
+	}
+	return dst
+}
+
+// commonPrefixLength returns the length of the common prefix of both strings
+func commonPrefixLength(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
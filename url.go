@@ -0,0 +1,127 @@
+package lightning
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// URL generates the path of the named route with the given params and the optional query params.
+// Optional params and optional groups are left out if their params are missing, wildcards keep their slashes.
+// The params must fulfill the constraints of the route, otherwise the URL wouldn't match the route.
+//  app.Get("/users/:id/posts/:post?", handler).Name("user.posts")
+//  app.URL("user.posts", lightning.Map{"id": 1}, lightning.Map{"page": 2}) // "/users/1/posts?page=2"
+func (app *App) URL(name string, params Map, query ...Map) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("url: route name must not be empty")
+	}
+	route := app.GetRoute(name)
+	if route.Name != name {
+		return "", fmt.Errorf("url: route %q not found", name)
+	}
+
+	parser := parseRoute(route.Path)
+	segs := parser.segs
+	// Optional groups: use the variant with the most given params, the shorter variant wins a tie
+	if len(parser.variants) > 0 {
+		segs = nil
+		best := -1
+		for _, variant := range parser.variants {
+			if used, ok := usedURLParams(variant.parser.segs, params); ok && used >= best {
+				segs, best = variant.parser.segs, used
+			}
+		}
+		if segs == nil {
+			return "", fmt.Errorf("url: missing params for route %q", name)
+		}
+	}
+
+	var sb strings.Builder
+	for _, segment := range segs {
+		if !segment.IsParam {
+			sb.WriteString(segment.Const)
+			continue
+		}
+		value, ok := urlParam(params, segment.ParamName)
+		if !ok {
+			if !segment.IsOptional {
+				return "", fmt.Errorf("url: missing param %q for route %q", segment.ParamName, name)
+			}
+			continue
+		}
+		if !segment.checkConstraints(value) {
+			return "", fmt.Errorf("url: param %q doesn't match the constraints of route %q", segment.ParamName, name)
+		}
+		sb.WriteString(escapePathParam(value, segment.IsGreedy))
+	}
+
+	path := sb.String()
+	// An optional param at the end leaves a trailing slash, e.g. "/users/:id?"
+	if len(path) > 1 && path[len(path)-1] == '/' && route.Path[len(route.Path)-1] != '/' {
+		path = utils.TrimRight(path, '/')
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	if len(query) > 0 {
+		values := url.Values{}
+		for _, q := range query {
+			for key, val := range q {
+				switch v := val.(type) {
+				case []string:
+					values[key] = append(values[key], v...)
+				default:
+					values.Add(key, fmt.Sprint(v))
+				}
+			}
+		}
+		if encoded := values.Encode(); encoded != "" {
+			path += "?" + encoded
+		}
+	}
+	return path, nil
+}
+
+// escapePathParam escapes the value of a param for a path, wildcards can contain multiple path segments
+// and keep their slashes
+func escapePathParam(value string, greedy bool) string {
+	if !greedy {
+		return url.PathEscape(value)
+	}
+	parts := strings.Split(value, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+// usedURLParams counts the given params of the segments, ok is false if a required param is missing
+func usedURLParams(segs []*routeSegment, params Map) (used int, ok bool) {
+	for _, segment := range segs {
+		if !segment.IsParam {
+			continue
+		}
+		if _, found := urlParam(params, segment.ParamName); found {
+			used++
+		} else if !segment.IsOptional {
+			return used, false
+		}
+	}
+	return used, true
+}
+
+// urlParam returns the string value of the param, wildcards and plus params can be used with and without their number
+func urlParam(params Map, key string) (string, bool) {
+	val, ok := params[key]
+	if !ok && (key == "*1" || key == "+1") {
+		val, ok = params[key[:1]]
+	}
+	if !ok || val == nil {
+		return "", false
+	}
+	value := fmt.Sprint(val)
+	return value, value != ""
+}
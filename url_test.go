@@ -0,0 +1,128 @@
+package lightning
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// go test -run Test_App_URL
+func Test_App_URL(t *testing.T) {
+	t.Parallel()
+	app := New()
+	handler := func(req *Request, res *Response) error {
+		return nil
+	}
+	app.Get("/", handler).Name("home")
+	app.Get("/Users/:id<int>", handler).Name("user")
+	app.Get("/users/:id/posts/:post?", handler).Name("user.posts")
+	app.Get("/files/*", handler).Name("files")
+	app.Get("/assets/+", handler).Name("assets")
+	app.Get("/docs(/:lang)?/intro", handler).Name("docs")
+	app.Get("/shop/:category-:item.html", handler).Name("item")
+	app.Get("/v1\\:action", handler).Name("escaped")
+	app.Group("/api").Name("api.").Get("/search", handler).Name("search")
+
+	testCases := []struct {
+		name   string
+		params Map
+		query  []Map
+		url    string
+		err    string
+	}{
+		{name: "home", url: "/"},
+		{name: "user", params: Map{"id": 42}, url: "/Users/42"},
+		{name: "user.posts", params: Map{"id": 1, "post": "hello world"}, url: "/users/1/posts/hello%20world"},
+		{name: "user.posts", params: Map{"id": 1}, url: "/users/1/posts"},
+		{name: "user.posts", params: Map{"id": 1}, query: []Map{{"page": 2}}, url: "/users/1/posts?page=2"},
+		{name: "files", params: Map{"*": "css/main 1.css"}, url: "/files/css/main%201.css"},
+		{name: "files", params: Map{"*1": "js/app.js"}, url: "/files/js/app.js"},
+		{name: "files", url: "/files"},
+		{name: "assets", params: Map{"+": "img/logo.png"}, url: "/assets/img/logo.png"},
+		{name: "docs", params: Map{"lang": "de"}, url: "/docs/de/intro"},
+		{name: "docs", url: "/docs/intro"},
+		{name: "item", params: Map{"category": "shoes", "item": "sneaker"}, url: "/shop/shoes-sneaker.html"},
+		{name: "escaped", url: "/v1:action"},
+		{name: "api.search", query: []Map{{"q": "go"}, {"tag": []string{"a", "b"}}}, url: "/api/search?q=go&tag=a&tag=b"},
+		{name: "user", err: "url: missing param \"id\" for route \"user\""},
+		{name: "user", params: Map{"id": "abc"}, err: "url: param \"id\" doesn't match the constraints of route \"user\""},
+		{name: "assets", err: "url: missing param \"+1\" for route \"assets\""},
+		{name: "unknown", err: "url: route \"unknown\" not found"},
+		{name: "", err: "url: route name must not be empty"},
+	}
+	for _, tc := range testCases {
+		url, err := app.URL(tc.name, tc.params, tc.query...)
+		if tc.err != "" {
+			utils.AssertEqual(t, tc.err, err.Error(), tc.name)
+			continue
+		}
+		utils.AssertEqual(t, nil, err, tc.name)
+		utils.AssertEqual(t, tc.url, url, tc.name)
+	}
+}
+
+// go test -run Test_Response_RedirectToRoute
+func Test_Response_RedirectToRoute(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/users/:id", func(req *Request, res *Response) error {
+		return nil
+	}).Name("user")
+	app.Get("/old/:id", func(req *Request, res *Response) error {
+		return res.RedirectToRoute("user", Map{"id": req.Param("id")}, StatusMovedPermanently)
+	})
+	app.Get("/broken", func(req *Request, res *Response) error {
+		return res.RedirectToRoute("user", nil)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/old/7", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusMovedPermanently, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "/users/7", resp.Header.Get(HeaderLocation))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/broken", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusInternalServerError, resp.StatusCode, "Status code")
+	// a mounted app redirects to the routes of the parent app
+	sub := New()
+	sub.Get("/login", func(req *Request, res *Response) error {
+		return res.RedirectToRoute("user", Map{"id": 1})
+	})
+	sub.Get("/me", func(req *Request, res *Response) error {
+		return nil
+	}).Name("me")
+	sub.Get("/profile", func(req *Request, res *Response) error {
+		return res.RedirectToRoute("me", nil)
+	})
+	app.Mount("/auth", sub)
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/auth/login", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusFound, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "/users/1", resp.Header.Get(HeaderLocation))
+
+	// the mounted routes are resolved with their prefix
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/auth/profile", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusFound, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "/auth/me", resp.Header.Get(HeaderLocation))
+}
+
+// go test -v -run=^$ -bench=Benchmark_App_URL -benchmem -count=4
+func Benchmark_App_URL(b *testing.B) {
+	app := New()
+	app.Get("/users/:id/posts/:post?", func(req *Request, res *Response) error {
+		return nil
+	}).Name("user.posts")
+	params := Map{"id": 1, "post": 2}
+	var url string
+	var err error
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		url, err = app.URL("user.posts", params)
+	}
+	utils.AssertEqual(b, nil, err)
+	utils.AssertEqual(b, "/users/1/posts/2", url)
+}
@@ -0,0 +1,89 @@
+package lightning
+
+import (
+	"strings"
+
+	"github.com/ikidev/lightning/utils"
+)
+
+// Version is used for routes which only match requests of the given API version, the version
+// is extracted by Config.VersionExtractor and Config.DefaultVersion is used if the request has none.
+// Requests to a registered path with an unknown version are answered with 406 Not Acceptable.
+//  v2 := app.Version("2")
+//  v2.Get("/users", handler) // Accept: application/vnd.acme.v2+json or X-API-Version: 2
+func (app *App) Version(version string, handlers ...Handler) Router {
+	grp := &Group{app: app, version: parseVersion(version)}
+	if len(handlers) > 0 {
+		_ = app.register(methodUse, "/", grp, handlers...)
+	}
+	return grp
+}
+
+// Version is used for routes of the group which only match requests of the given API version.
+//  api := app.Group("/api")
+//  api.Version("2").Get("/users", handler)
+func (grp *Group) Version(version string, handlers ...Handler) Router {
+	v := &Group{app: grp.app, prefix: grp.prefix, name: grp.name, host: grp.host, version: parseVersion(version)}
+	if len(handlers) > 0 {
+		_ = grp.app.register(methodUse, getGroupPath(grp.prefix, "/"), v, handlers...)
+	}
+	return v
+}
+
+// parseVersion validates the version of a versioned group
+func parseVersion(version string) string {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		panic("version: version must not be empty\n")
+	}
+	return version
+}
+
+// requestVersion returns the requested version, it's only extracted once per request
+func (c *Ctx) requestVersion() string {
+	if !c.versionDetected {
+		c.versionDetected = true
+		c.version = c.router.config.VersionExtractor(c.req)
+		if c.version == "" {
+			c.version = c.router.config.DefaultVersion
+		}
+	}
+	return c.version
+}
+
+// DefaultVersionExtractor returns the version of the X-API-Version header, otherwise the version
+// of a vendor specific media type in the Accept header, e.g. "application/vnd.acme.v2+json"
+// or "application/vnd.acme+json; version=2".
+func DefaultVersionExtractor(req *Request) string {
+	if version := strings.TrimSpace(req.Header.Get(HeaderXAPIVersion)); version != "" {
+		return version
+	}
+	for _, spec := range strings.Split(req.Header.Get(HeaderAccept), ",") {
+		mediaType, params := spec, ""
+		if i := strings.IndexByte(spec, ';'); i != -1 {
+			mediaType, params = spec[:i], spec[i+1:]
+		}
+		mediaType = strings.TrimSpace(mediaType)
+		// Only vendor specific media types carry a version
+		slash := strings.Index(mediaType, "/vnd.")
+		if slash == -1 {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			param = strings.TrimSpace(param)
+			if len(param) > 8 && utils.EqualFold(param[:8], "version=") {
+				return utils.Trim(param[8:], '"')
+			}
+		}
+		// The version is the last part of the vendor name, e.g. "vnd.acme.v2"
+		name := mediaType[slash+1:]
+		if plus := strings.IndexByte(name, '+'); plus != -1 {
+			name = name[:plus]
+		}
+		part := name[strings.LastIndexAny(name, ".-")+1:]
+		if len(part) > 1 && (part[0] == 'v' || part[0] == 'V') && part[1] >= '0' && part[1] <= '9' {
+			return part[1:]
+		}
+	}
+	return ""
+}
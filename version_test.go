@@ -0,0 +1,141 @@
+package lightning
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ikidev/lightning/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// go test -run Test_Version_Routing
+func Test_Version_Routing(t *testing.T) {
+	t.Parallel()
+	app := New(Config{DefaultVersion: "1"})
+
+	app.Version("1").Get("/users", func(req *Request, res *Response) error {
+		return res.String("v1")
+	})
+	v2 := app.Version("2", func(req *Request, res *Response) error {
+		res.Header.Set("X-Version", "2")
+		return req.Next()
+	})
+	v2.Get("/users", func(req *Request, res *Response) error {
+		return res.String("v2")
+	})
+	app.Group("/api").Version("3").Get("/users", func(req *Request, res *Response) error {
+		return res.String("v3")
+	})
+	app.Get("/health", func(req *Request, res *Response) error {
+		return res.String("ok")
+	})
+
+	testCases := []struct {
+		url     string
+		header  string
+		value   string
+		code    int
+		body    string
+		version string
+	}{
+		{url: "/users", code: StatusOK, body: "v1"},
+		{url: "/users", header: HeaderXAPIVersion, value: "1", code: StatusOK, body: "v1"},
+		{url: "/users", header: HeaderXAPIVersion, value: "2", code: StatusOK, body: "v2", version: "2"},
+		{url: "/users", header: HeaderAccept, value: "application/vnd.acme.v2+json", code: StatusOK, body: "v2", version: "2"},
+		{url: "/users", header: HeaderAccept, value: "text/html, application/vnd.acme+json; version=2", code: StatusOK, body: "v2", version: "2"},
+		{url: "/users", header: HeaderAccept, value: "application/json", code: StatusOK, body: "v1"},
+		{url: "/users", header: HeaderXAPIVersion, value: "4", code: StatusNotAcceptable, body: "Not Acceptable"},
+		{url: "/api/users", header: HeaderXAPIVersion, value: "3", code: StatusOK, body: "v3"},
+		{url: "/api/users", code: StatusNotAcceptable, body: "Not Acceptable"},
+		{url: "/health", header: HeaderXAPIVersion, value: "4", code: StatusOK, body: "ok"},
+		{url: "/unknown", header: HeaderXAPIVersion, value: "2", code: StatusNotFound, body: "Cannot GET /unknown", version: "2"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(MethodGet, tc.url, nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, tc.url)
+		utils.AssertEqual(t, tc.code, resp.StatusCode, tc.url+" "+tc.value)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.body, string(body), tc.url+" "+tc.value)
+		utils.AssertEqual(t, tc.version, resp.Header.Get("X-Version"), tc.url+" "+tc.value)
+	}
+}
+
+// go test -run Test_Version_Extractor
+func Test_Version_Extractor(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		VersionExtractor: func(req *Request) string {
+			return req.Query("v")
+		},
+	})
+	app.Version("2").Get("/", func(req *Request, res *Response) error {
+		return res.String("v2")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/?v=2", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	// Without a default version the request only matches routes without a version
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNotAcceptable, resp.StatusCode)
+
+	utils.AssertEqual(t, "2", app.Stack()[app.methodInt(MethodGet)][0].Version)
+}
+
+// go test -run Test_DefaultVersionExtractor
+func Test_DefaultVersionExtractor(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	testCases := []struct {
+		header  string
+		value   string
+		version string
+	}{
+		{header: HeaderXAPIVersion, value: " 2 ", version: "2"},
+		{header: HeaderAccept, value: "application/vnd.acme.v2+json", version: "2"},
+		{header: HeaderAccept, value: "application/vnd.acme-v10+xml;q=0.9", version: "10"},
+		{header: HeaderAccept, value: "application/vnd.acme.V3", version: "3"},
+		{header: HeaderAccept, value: `application/vnd.acme+json; version="2.1"`, version: "2.1"},
+		{header: HeaderAccept, value: "application/vnd.acme.vendor+json", version: ""},
+		{header: HeaderAccept, value: "application/json; version=2", version: ""},
+		{header: HeaderAccept, value: "", version: ""},
+	}
+
+	for _, tc := range testCases {
+		req, _ := app.AcquireReqRes(&fasthttp.RequestCtx{})
+		req.ctx.fasthttp.Request.Header.Set(tc.header, tc.value)
+		utils.AssertEqual(t, tc.version, DefaultVersionExtractor(req), tc.value)
+		app.ReleaseCtx(req.ctx)
+	}
+}
+
+// go test -run Test_Version_Panic
+func Test_Version_Panic(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		utils.AssertEqual(t, "version: version must not be empty\n", recover())
+	}()
+	New().Version(" ")
+}
+
+// go test -run Test_Version_Conflicts
+func Test_Version_Conflicts(t *testing.T) {
+	t.Parallel()
+	app := New(Config{RouteConflictPolicy: RouteConflictWarn})
+	app.Version("1").Add(MethodGet, "/users", testEmptyHandler)
+	app.Version("2").Add(MethodGet, "/users", testEmptyHandler)
+	app.Add(MethodGet, "/health", testEmptyHandler)
+	app.Version("2").Add(MethodGet, "/users", testEmptyHandler)
+	app.startupProcess()
+	utils.AssertEqual(t, []string{"GET /users is registered twice"}, app.routeConflicts)
+}